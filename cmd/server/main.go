@@ -17,6 +17,9 @@ import (
 
 	"github.com/joho/godotenv"
 	configaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/config_access"
+	jwtaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/jwt_access"
+	mtlsaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/mtls_access"
+	webhookaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access/webhook_access"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/cmd"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
@@ -404,6 +407,7 @@ func main() {
 	// Register the shared token store once so all components use the same persistence backend.
 	if usePostgresStore {
 		sdkAuth.RegisterTokenStore(pgStoreInst)
+		usage.RegisterStoreBackend(config.UsageStoragePostgres, pgStoreInst.OpenUsageStore)
 	} else if useObjectStore {
 		sdkAuth.RegisterTokenStore(objectStoreInst)
 	} else if useGitStore {
@@ -414,6 +418,9 @@ func main() {
 
 	// Register built-in access providers before constructing services.
 	configaccess.Register()
+	jwtaccess.Register()
+	webhookaccess.Register()
+	mtlsaccess.Register()
 
 	// Handle different command modes based on the provided flags.
 
@@ -2,10 +2,13 @@ package configaccess
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
+	internalaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
 )
@@ -22,9 +25,19 @@ func Register() {
 type provider struct {
 	name string
 	keys map[string]struct{}
+	// clientKeys indexes plaintext client-keys entries by key.
+	clientKeys map[string]sdkconfig.ClientKey
+	// hashedKeys holds the client-keys entries stored as a hash. They are only matched by the hash
+	// of a presented credential, never by the credential itself, so the stored hash is not a key.
+	hashedKeys []hashedClientKey
 }
 
-func newProvider(cfg *sdkconfig.AccessProvider, _ *sdkconfig.SDKConfig) (sdkaccess.Provider, error) {
+type hashedClientKey struct {
+	hash  string
+	entry sdkconfig.ClientKey
+}
+
+func newProvider(cfg *sdkconfig.AccessProvider, root *sdkconfig.SDKConfig) (sdkaccess.Provider, error) {
 	name := cfg.Name
 	if name == "" {
 		name = sdkconfig.DefaultAccessProviderName
@@ -36,7 +49,21 @@ func newProvider(cfg *sdkconfig.AccessProvider, _ *sdkconfig.SDKConfig) (sdkacce
 		}
 		keys[key] = struct{}{}
 	}
-	return &provider{name: name, keys: keys}, nil
+	clientKeys := make(map[string]sdkconfig.ClientKey)
+	var hashedKeys []hashedClientKey
+	if root != nil {
+		for _, entry := range root.ClientKeys {
+			if entry.Principal() == "" {
+				continue
+			}
+			if key := strings.TrimSpace(entry.Key); key != "" {
+				clientKeys[key] = entry
+			} else {
+				hashedKeys = append(hashedKeys, hashedClientKey{hash: strings.ToLower(strings.TrimSpace(entry.KeyHash)), entry: entry})
+			}
+		}
+	}
+	return &provider{name: name, keys: keys, clientKeys: clientKeys, hashedKeys: hashedKeys}, nil
 }
 
 func (p *provider) Identifier() string {
@@ -50,45 +77,44 @@ func (p *provider) Authenticate(_ context.Context, r *http.Request) (*sdkaccess.
 	if p == nil {
 		return nil, sdkaccess.ErrNotHandled
 	}
-	if len(p.keys) == 0 {
+	if len(p.keys) == 0 && len(p.clientKeys) == 0 && len(p.hashedKeys) == 0 {
 		return nil, sdkaccess.ErrNotHandled
 	}
-	authHeader := r.Header.Get("Authorization")
-	authHeaderGoogle := r.Header.Get("X-Goog-Api-Key")
-	authHeaderAnthropic := r.Header.Get("X-Api-Key")
-	queryKey := ""
-	queryAuthToken := ""
-	if r.URL != nil {
-		queryKey = r.URL.Query().Get("key")
-		queryAuthToken = r.URL.Query().Get("auth_token")
-	}
-	if authHeader == "" && authHeaderGoogle == "" && authHeaderAnthropic == "" && queryKey == "" && queryAuthToken == "" {
+	candidates := sdkaccess.ExtractCredentials(r)
+	if len(candidates) == 0 {
 		return nil, sdkaccess.ErrNoCredentials
 	}
 
-	apiKey := extractBearerToken(authHeader)
-
-	candidates := []struct {
-		value  string
-		source string
-	}{
-		{apiKey, "authorization"},
-		{authHeaderGoogle, "x-goog-api-key"},
-		{authHeaderAnthropic, "x-api-key"},
-		{queryKey, "query-key"},
-		{queryAuthToken, "query-auth-token"},
-	}
-
+	now := time.Now()
 	for _, candidate := range candidates {
-		if candidate.value == "" {
-			continue
+		entry, ok := p.clientKeys[candidate.Value]
+		if !ok {
+			entry, ok = p.lookupHashed(candidate.Value)
 		}
-		if _, ok := p.keys[candidate.value]; ok {
+		if ok {
+			if entry.Expired(now) {
+				continue
+			}
+			metadata := map[string]string{"source": candidate.Source}
+			if len(entry.Scopes) > 0 {
+				metadata[sdkaccess.MetadataScopes] = strings.Join(entry.Scopes, ",")
+			}
+			if entry.ID != "" {
+				metadata["client-key-id"] = entry.ID
+				internalaccess.GetKeyActivity().Touch(entry.ID, now)
+			}
 			return &sdkaccess.Result{
 				Provider:  p.Identifier(),
-				Principal: candidate.value,
+				Principal: entry.Principal(),
+				Metadata:  metadata,
+			}, nil
+		}
+		if _, ok = p.keys[candidate.Value]; ok {
+			return &sdkaccess.Result{
+				Provider:  p.Identifier(),
+				Principal: candidate.Value,
 				Metadata: map[string]string{
-					"source": candidate.source,
+					"source": candidate.Source,
 				},
 			}, nil
 		}
@@ -97,16 +123,16 @@ func (p *provider) Authenticate(_ context.Context, r *http.Request) (*sdkaccess.
 	return nil, sdkaccess.ErrInvalidCredential
 }
 
-func extractBearerToken(header string) string {
-	if header == "" {
-		return ""
-	}
-	parts := strings.SplitN(header, " ", 2)
-	if len(parts) != 2 {
-		return header
+// lookupHashed returns the hashed client key whose hash matches secret.
+func (p *provider) lookupHashed(secret string) (sdkconfig.ClientKey, bool) {
+	if len(p.hashedKeys) == 0 {
+		return sdkconfig.ClientKey{}, false
 	}
-	if strings.ToLower(parts[0]) != "bearer" {
-		return header
+	digest := []byte(sdkconfig.HashClientKey(secret))
+	for _, hashed := range p.hashedKeys {
+		if subtle.ConstantTimeCompare(digest, []byte(hashed.hash)) == 1 {
+			return hashed.entry, true
+		}
 	}
-	return strings.TrimSpace(parts[1])
+	return sdkconfig.ClientKey{}, false
 }
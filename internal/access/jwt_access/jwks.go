@@ -0,0 +1,246 @@
+package jwtaccess
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// jwksMinRefresh throttles refreshes triggered by tokens signed with an unknown key ID.
+	jwksMinRefresh = 30 * time.Second
+	jwksMaxBytes   = 1 << 20
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type verificationKey struct {
+	kid string
+	alg string
+	key crypto.PublicKey
+}
+
+// keySet caches the verification keys of a JWKS file or URL.
+type keySet struct {
+	url      string
+	file     string
+	interval time.Duration
+	client   *http.Client
+
+	mu        sync.Mutex
+	keys      []verificationKey
+	loadedAt  time.Time
+	attempted time.Time
+	// refreshing is closed when the refresh in flight completes; nil when none is.
+	refreshing chan struct{}
+}
+
+func newKeySet(url, file string, interval time.Duration) *keySet {
+	return &keySet{url: url, file: file, interval: interval, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// lookup returns the keys that may have signed a token with kid, refreshing the set when it is
+// stale or does not know kid. One refresh runs at a time, detached from the requests waiting for
+// it; requests that already have a matching key do not wait.
+func (s *keySet) lookup(ctx context.Context, kid string) []verificationKey {
+	s.mu.Lock()
+	now := time.Now()
+	stale := s.loadedAt.IsZero() || now.Sub(s.loadedAt) >= s.interval
+	matched := matchKeys(s.keys, kid)
+	if !stale && kid != "" && len(matched) == 0 {
+		stale = true
+	}
+	if stale && s.refreshing == nil && now.Sub(s.attempted) >= jwksMinRefresh {
+		s.attempted = now
+		s.refreshing = make(chan struct{})
+		go s.refresh(s.refreshing)
+	}
+	done := s.refreshing
+	s.mu.Unlock()
+	if len(matched) > 0 || done == nil {
+		return matched
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return matchKeys(s.keys, kid)
+}
+
+// refresh reloads the key set and closes done.
+func (s *keySet) refresh(done chan struct{}) {
+	keys, err := s.fetch(context.Background())
+	s.mu.Lock()
+	if err != nil {
+		log.Warnf("jwt access: failed to load JWKS: %v", err)
+	} else {
+		s.keys = keys
+		s.loadedAt = time.Now()
+	}
+	s.refreshing = nil
+	s.mu.Unlock()
+	close(done)
+}
+
+func matchKeys(keys []verificationKey, kid string) []verificationKey {
+	if kid == "" {
+		return keys
+	}
+	var out []verificationKey
+	for _, key := range keys {
+		if key.kid == kid {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+func (s *keySet) fetch(ctx context.Context) ([]verificationKey, error) {
+	var data []byte
+	if s.file != "" {
+		raw, err := os.ReadFile(s.file)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", s.file, err)
+		}
+		data = raw
+	} else {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if errClose := resp.Body.Close(); errClose != nil {
+				log.Errorf("jwt access: close JWKS response body: %v", errClose)
+			}
+		}()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GET %s: status %d", s.url, resp.StatusCode)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, jwksMaxBytes))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parseJWKS(data)
+}
+
+func parseJWKS(data []byte) ([]verificationKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse JWKS: %w", err)
+	}
+	keys := make([]verificationKey, 0, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			log.Warnf("jwt access: skipping JWKS key %q: %v", k.Kid, err)
+			continue
+		}
+		keys = append(keys, verificationKey{kid: k.Kid, alg: k.Alg, key: pub})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS contains no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,354 @@
+// Package jwtaccess provides the jwt access provider, which authenticates clients with bearer JWTs
+// issued by an external identity provider and verified against its JWKS.
+package jwtaccess
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	internalaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProviderType is the access provider type handled by this package.
+const ProviderType = "jwt"
+
+const (
+	defaultClockSkew   = time.Minute
+	defaultJWKSRefresh = time.Hour
+)
+
+var defaultAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var registerOnce sync.Once
+
+// Register makes the jwt provider type available to the access manager.
+func Register() {
+	registerOnce.Do(func() {
+		sdkaccess.RegisterProvider(ProviderType, newProvider)
+	})
+}
+
+type provider struct {
+	name           string
+	keys           *keySet
+	issuer         string
+	audiences      []string
+	algorithms     map[string]struct{}
+	clockSkew      time.Duration
+	principalClaim string
+	metadataClaims []string
+	policyClaims   map[string]string
+}
+
+func newProvider(cfg *sdkconfig.AccessProvider, _ *sdkconfig.SDKConfig) (sdkaccess.Provider, error) {
+	options := cfg.Config
+	jwksURL := internalaccess.ConfigString(options, "jwks-url")
+	jwksFile := internalaccess.ConfigString(options, "jwks-file")
+	if (jwksURL == "") == (jwksFile == "") {
+		return nil, fmt.Errorf("jwt provider requires exactly one of jwks-url or jwks-file")
+	}
+	algorithms := internalaccess.ConfigStrings(options, "algorithms")
+	if len(algorithms) == 0 {
+		algorithms = defaultAlgorithms
+	}
+	allowed := make(map[string]struct{}, len(algorithms))
+	for _, alg := range algorithms {
+		if _, ok := signingHash(alg); !ok {
+			return nil, fmt.Errorf("jwt provider: unsupported algorithm %q", alg)
+		}
+		allowed[alg] = struct{}{}
+	}
+	refresh := time.Duration(internalaccess.ConfigInt(options, "jwks-refresh-seconds", 0)) * time.Second
+	if refresh <= 0 {
+		refresh = defaultJWKSRefresh
+	}
+	skew := defaultClockSkew
+	if seconds := internalaccess.ConfigInt(options, "clock-skew-seconds", -1); seconds >= 0 {
+		skew = time.Duration(seconds) * time.Second
+	}
+	principalClaim := internalaccess.ConfigString(options, "principal-claim")
+	if principalClaim == "" {
+		principalClaim = "sub"
+	}
+	policyClaims := make(map[string]string)
+	for metadataKey, option := range map[string]string{
+		sdkaccess.MetadataAllowedModels:    "allowed-models-claim",
+		sdkaccess.MetadataDeniedModels:     "denied-models-claim",
+		sdkaccess.MetadataAllowedProviders: "allowed-providers-claim",
+		sdkaccess.MetadataTenant:           "tenant-claim",
+	} {
+		if claim := internalaccess.ConfigString(options, option); claim != "" {
+			policyClaims[metadataKey] = claim
+		}
+	}
+	name := cfg.Name
+	if name == "" {
+		name = ProviderType
+	}
+	return &provider{
+		name:           name,
+		keys:           newKeySet(jwksURL, jwksFile, refresh),
+		issuer:         internalaccess.ConfigString(options, "issuer"),
+		audiences:      internalaccess.ConfigStrings(options, "audience"),
+		algorithms:     allowed,
+		clockSkew:      skew,
+		principalClaim: principalClaim,
+		metadataClaims: internalaccess.ConfigStrings(options, "metadata-claims"),
+		policyClaims:   policyClaims,
+	}, nil
+}
+
+func (p *provider) Identifier() string {
+	if p == nil || p.name == "" {
+		return ProviderType
+	}
+	return p.name
+}
+
+// Authenticate accepts the first credential of the request that looks like a JWT. Other
+// credentials are left to the remaining providers.
+func (p *provider) Authenticate(ctx context.Context, r *http.Request) (*sdkaccess.Result, error) {
+	if p == nil {
+		return nil, sdkaccess.ErrNotHandled
+	}
+	candidates := sdkaccess.ExtractCredentials(r)
+	if len(candidates) == 0 {
+		return nil, sdkaccess.ErrNoCredentials
+	}
+	for _, candidate := range candidates {
+		if strings.Count(candidate.Value, ".") != 2 {
+			continue
+		}
+		claims, err := p.verify(ctx, candidate.Value, time.Now())
+		if err != nil {
+			log.Debugf("jwt access: rejected token from %s: %v", candidate.Source, err)
+			return nil, sdkaccess.ErrInvalidCredential
+		}
+		principal := claimString(claims[p.principalClaim])
+		if principal == "" {
+			log.Debugf("jwt access: token has no %s claim", p.principalClaim)
+			return nil, sdkaccess.ErrInvalidCredential
+		}
+		metadata := map[string]string{"source": candidate.Source}
+		for _, claim := range p.metadataClaims {
+			if value := claimString(claims[claim]); value != "" {
+				metadata[claim] = value
+			}
+		}
+		for metadataKey, claim := range p.policyClaims {
+			if value := claimString(claims[claim]); value != "" {
+				metadata[metadataKey] = value
+			}
+		}
+		return &sdkaccess.Result{Provider: p.Identifier(), Principal: principal, Metadata: metadata}, nil
+	}
+	return nil, sdkaccess.ErrNotHandled
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verify checks the signature and registered claims of token and returns its claims.
+func (p *provider) verify(ctx context.Context, token string, now time.Time) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("decode header: %w", err)
+	}
+	var header jwtHeader
+	if err = json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("parse header: %w", err)
+	}
+	if _, ok := p.algorithms[header.Alg]; !ok {
+		return nil, fmt.Errorf("algorithm %q not allowed", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range p.keys.lookup(ctx, header.Kid) {
+		if key.alg != "" && key.alg != header.Alg {
+			continue
+		}
+		if verifySignature(header.Alg, key.key, signed, signature) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("signature verification failed")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(payload)))
+	decoder.UseNumber()
+	var claims map[string]any
+	if err = decoder.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("parse claims: %w", err)
+	}
+	if err = p.validateClaims(claims, now); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (p *provider) validateClaims(claims map[string]any, now time.Time) error {
+	exp, ok := claimTime(claims["exp"])
+	if !ok {
+		return errors.New("missing exp claim")
+	}
+	if now.After(exp.Add(p.clockSkew)) {
+		return errors.New("token expired")
+	}
+	if nbf, okNbf := claimTime(claims["nbf"]); okNbf && now.Add(p.clockSkew).Before(nbf) {
+		return errors.New("token not yet valid")
+	}
+	if p.issuer != "" && claimString(claims["iss"]) != p.issuer {
+		return fmt.Errorf("unexpected issuer %q", claimString(claims["iss"]))
+	}
+	if len(p.audiences) > 0 {
+		matched := false
+		for _, aud := range claimStrings(claims["aud"]) {
+			for _, want := range p.audiences {
+				if aud == want {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return errors.New("audience mismatch")
+		}
+	}
+	return nil
+}
+
+// signingHash returns the hash of a JWS algorithm; EdDSA signs the message itself.
+func signingHash(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case "RS256", "PS256", "ES256":
+		return crypto.SHA256, true
+	case "RS384", "PS384", "ES384":
+		return crypto.SHA384, true
+	case "RS512", "PS512", "ES512":
+		return crypto.SHA512, true
+	case "EdDSA":
+		return 0, true
+	}
+	return 0, false
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	hash, _ := signingHash(alg)
+	var digest []byte
+	if hash != 0 {
+		h := hash.New()
+		h.Write(signed)
+		digest = h.Sum(nil)
+	}
+	switch alg[:2] {
+	case "RS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+	case "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		return rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case "Ed":
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errors.New("key type mismatch")
+		}
+		if !ed25519.Verify(pub, signed, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm %q", alg)
+}
+
+func claimTime(value any) (time.Time, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := number.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// claimString renders a claim as a string; lists are joined with commas.
+func claimString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		return strings.Join(claimStrings(v), ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// claimStrings reads a claim holding a string or a list of strings.
+func claimStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package access
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// KeyActivityFileName is the file in the auth directory holding client key last-used times.
+const KeyActivityFileName = ".client-key-activity"
+
+// keyActivityFlushDelay batches last-used updates so busy keys do not rewrite the file per request.
+const keyActivityFlushDelay = 30 * time.Second
+
+// KeyActivity remembers when each client key ID was last used and persists it across restarts.
+type KeyActivity struct {
+	mu        sync.Mutex
+	path      string
+	lastUsed  map[string]time.Time
+	scheduled bool
+}
+
+var defaultKeyActivity = &KeyActivity{lastUsed: make(map[string]time.Time)}
+
+// GetKeyActivity returns the process-wide client key activity tracker.
+func GetKeyActivity() *KeyActivity {
+	return defaultKeyActivity
+}
+
+// SetStatePath loads previously persisted activity from path and persists future updates there.
+func (a *KeyActivity) SetStatePath(path string) {
+	if a == nil {
+		return
+	}
+	var stored map[string]time.Time
+	if data, err := os.ReadFile(path); err == nil {
+		if errUnmarshal := json.Unmarshal(data, &stored); errUnmarshal != nil {
+			log.Warnf("client key activity: ignoring unreadable %s: %v", path, errUnmarshal)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("client key activity: failed to read %s: %v", path, err)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.path = path
+	for id, at := range stored {
+		if at.After(a.lastUsed[id]) {
+			a.lastUsed[id] = at
+		}
+	}
+}
+
+// Touch records that the key with id was used at now.
+func (a *KeyActivity) Touch(id string, now time.Time) {
+	if a == nil || id == "" {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lastUsed[id] = now
+	if a.path != "" && !a.scheduled {
+		a.scheduled = true
+		time.AfterFunc(keyActivityFlushDelay, a.flush)
+	}
+}
+
+// LastUsed returns when the key with id was last used.
+func (a *KeyActivity) LastUsed(id string) (time.Time, bool) {
+	if a == nil {
+		return time.Time{}, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	at, ok := a.lastUsed[id]
+	return at, ok
+}
+
+// Forget drops the activity of a revoked key.
+func (a *KeyActivity) Forget(id string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	_, ok := a.lastUsed[id]
+	delete(a.lastUsed, id)
+	if ok && a.path != "" && !a.scheduled {
+		a.scheduled = true
+		time.AfterFunc(keyActivityFlushDelay, a.flush)
+	}
+	a.mu.Unlock()
+}
+
+func (a *KeyActivity) flush() {
+	a.mu.Lock()
+	a.scheduled = false
+	path := a.path
+	data, err := json.Marshal(a.lastUsed)
+	a.mu.Unlock()
+	if err != nil || path == "" {
+		return
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		log.Warnf("client key activity: failed to write %s: %v", tmp, err)
+		return
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		log.Warnf("client key activity: failed to replace %s: %v", filepath.Base(path), err)
+	}
+}
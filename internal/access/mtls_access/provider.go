@@ -0,0 +1,128 @@
+// Package mtlsaccess provides the mtls access provider, which authenticates clients by the TLS
+// client certificate the server verified against tls.client-ca-file.
+package mtlsaccess
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	internalaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// ProviderType is the access provider type handled by this package.
+const ProviderType = "mtls"
+
+// Certificate fields an identity can be read from.
+const (
+	fieldURISAN   = "uri-san"
+	fieldDNSSAN   = "dns-san"
+	fieldEmailSAN = "email-san"
+	fieldCN       = "cn"
+)
+
+var defaultIdentityFields = []string{fieldURISAN, fieldDNSSAN, fieldEmailSAN, fieldCN}
+
+var registerOnce sync.Once
+
+// Register makes the mtls provider type available to the access manager.
+func Register() {
+	registerOnce.Do(func() {
+		sdkaccess.RegisterProvider(ProviderType, newProvider)
+	})
+}
+
+type provider struct {
+	name   string
+	fields []string
+	// principals maps certificate identities to principals; when set, other identities are rejected.
+	principals map[string]string
+}
+
+func newProvider(cfg *sdkconfig.AccessProvider, _ *sdkconfig.SDKConfig) (sdkaccess.Provider, error) {
+	options := cfg.Config
+	fields := internalaccess.ConfigStrings(options, "identity-fields")
+	for i, field := range fields {
+		fields[i] = strings.ToLower(field)
+		switch fields[i] {
+		case fieldURISAN, fieldDNSSAN, fieldEmailSAN, fieldCN:
+		default:
+			return nil, fmt.Errorf("mtls provider: unknown identity field %q", field)
+		}
+	}
+	if len(fields) == 0 {
+		fields = defaultIdentityFields
+	}
+	name := cfg.Name
+	if name == "" {
+		name = ProviderType
+	}
+	return &provider{
+		name:       name,
+		fields:     fields,
+		principals: internalaccess.ConfigStringMap(options, "principals"),
+	}, nil
+}
+
+func (p *provider) Identifier() string {
+	if p == nil || p.name == "" {
+		return ProviderType
+	}
+	return p.name
+}
+
+// Authenticate maps the verified client certificate of the connection to a principal. Requests
+// without one are left to the remaining providers.
+func (p *provider) Authenticate(_ context.Context, r *http.Request) (*sdkaccess.Result, error) {
+	if p == nil || r == nil || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, sdkaccess.ErrNotHandled
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	for _, identity := range p.identities(cert) {
+		principal := identity
+		if p.principals != nil {
+			mapped, ok := p.principals[identity]
+			if !ok {
+				continue
+			}
+			principal = mapped
+		}
+		return &sdkaccess.Result{
+			Provider:  p.Identifier(),
+			Principal: principal,
+			Metadata: map[string]string{
+				"source":   "client-certificate",
+				"identity": identity,
+				"subject":  cert.Subject.String(),
+			},
+		}, nil
+	}
+	return nil, sdkaccess.ErrInvalidCredential
+}
+
+// identities lists the certificate identities in the configured field order.
+func (p *provider) identities(cert *x509.Certificate) []string {
+	var out []string
+	for _, field := range p.fields {
+		switch field {
+		case fieldURISAN:
+			for _, uri := range cert.URIs {
+				out = append(out, uri.String())
+			}
+		case fieldDNSSAN:
+			out = append(out, cert.DNSNames...)
+		case fieldEmailSAN:
+			out = append(out, cert.EmailAddresses...)
+		case fieldCN:
+			if cert.Subject.CommonName != "" {
+				out = append(out, cert.Subject.CommonName)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package access
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ConfigString reads a string option from an access provider config block.
+func ConfigString(cfg map[string]any, key string) string {
+	if cfg == nil {
+		return ""
+	}
+	switch value := cfg[key].(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case nil:
+		return ""
+	default:
+		return strings.TrimSpace(fmt.Sprint(value))
+	}
+}
+
+// ConfigStrings reads a list option from an access provider config block. A single string is
+// accepted as a one-element list.
+func ConfigStrings(cfg map[string]any, key string) []string {
+	if cfg == nil {
+		return nil
+	}
+	var out []string
+	switch value := cfg[key].(type) {
+	case string:
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	case []string:
+		for _, item := range value {
+			if trimmed := strings.TrimSpace(item); trimmed != "" {
+				out = append(out, trimmed)
+			}
+		}
+	case []any:
+		for _, item := range value {
+			if item == nil {
+				continue
+			}
+			if trimmed := strings.TrimSpace(fmt.Sprint(item)); trimmed != "" {
+				out = append(out, trimmed)
+			}
+		}
+	}
+	return out
+}
+
+// ConfigInt reads an integer option from an access provider config block, returning def when it
+// is absent or malformed.
+func ConfigInt(cfg map[string]any, key string, def int) int {
+	if cfg == nil {
+		return def
+	}
+	switch value := cfg[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	case string:
+		if parsed, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+// ConfigStringMap reads a string-to-string map option from an access provider config block.
+func ConfigStringMap(cfg map[string]any, key string) map[string]string {
+	if cfg == nil {
+		return nil
+	}
+	out := make(map[string]string)
+	switch value := cfg[key].(type) {
+	case map[string]any:
+		for k, v := range value {
+			out[k] = strings.TrimSpace(fmt.Sprint(v))
+		}
+	case map[string]string:
+		for k, v := range value {
+			out[k] = strings.TrimSpace(v)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,268 @@
+// Package webhookaccess provides the webhook access provider, which delegates credential checks to
+// an external HTTP authorization service and caches its decisions.
+package webhookaccess
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	internalaccess "github.com/router-for-me/CLIProxyAPI/v6/internal/access"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProviderType is the access provider type handled by this package.
+const ProviderType = "webhook"
+
+const (
+	defaultTimeout     = 5 * time.Second
+	defaultCacheTTL    = time.Minute
+	defaultDenyTTL     = 10 * time.Second
+	maxCacheEntries    = 10000
+	maxResponseBytes   = 64 << 10
+	cacheSweepInterval = time.Minute
+)
+
+var registerOnce sync.Once
+
+// Register makes the webhook provider type available to the access manager.
+func Register() {
+	registerOnce.Do(func() {
+		sdkaccess.RegisterProvider(ProviderType, newProvider)
+	})
+}
+
+// decision is a cached webhook verdict; a nil result means the credential was denied.
+type decision struct {
+	result  *sdkaccess.Result
+	expires time.Time
+}
+
+type provider struct {
+	name     string
+	url      string
+	headers  map[string]string
+	client   *http.Client
+	allowTTL time.Duration
+	denyTTL  time.Duration
+
+	mu        sync.Mutex
+	cache     map[string]decision
+	lastSweep time.Time
+}
+
+func newProvider(cfg *sdkconfig.AccessProvider, _ *sdkconfig.SDKConfig) (sdkaccess.Provider, error) {
+	options := cfg.Config
+	url := internalaccess.ConfigString(options, "url")
+	if url == "" {
+		return nil, fmt.Errorf("webhook provider requires url")
+	}
+	timeout := time.Duration(internalaccess.ConfigInt(options, "timeout-seconds", 0)) * time.Second
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	allowTTL := defaultCacheTTL
+	if seconds := internalaccess.ConfigInt(options, "cache-ttl-seconds", -1); seconds >= 0 {
+		allowTTL = time.Duration(seconds) * time.Second
+	}
+	denyTTL := defaultDenyTTL
+	if seconds := internalaccess.ConfigInt(options, "deny-cache-ttl-seconds", -1); seconds >= 0 {
+		denyTTL = time.Duration(seconds) * time.Second
+	}
+	name := cfg.Name
+	if name == "" {
+		name = ProviderType
+	}
+	return &provider{
+		name:     name,
+		url:      url,
+		headers:  internalaccess.ConfigStringMap(options, "headers"),
+		client:   &http.Client{Timeout: timeout},
+		allowTTL: allowTTL,
+		denyTTL:  denyTTL,
+		cache:    make(map[string]decision),
+	}, nil
+}
+
+func (p *provider) Identifier() string {
+	if p == nil || p.name == "" {
+		return ProviderType
+	}
+	return p.name
+}
+
+// Authenticate asks the webhook about each credential of the request until one is allowed.
+// Webhook failures are not cached and reject the credential.
+func (p *provider) Authenticate(ctx context.Context, r *http.Request) (*sdkaccess.Result, error) {
+	if p == nil {
+		return nil, sdkaccess.ErrNotHandled
+	}
+	candidates := sdkaccess.ExtractCredentials(r)
+	if len(candidates) == 0 {
+		return nil, sdkaccess.ErrNoCredentials
+	}
+	for _, candidate := range candidates {
+		result, err := p.decide(ctx, candidate, time.Now())
+		if err != nil {
+			log.Warnf("webhook access %s: %v", p.Identifier(), err)
+			continue
+		}
+		if result != nil {
+			return result, nil
+		}
+	}
+	return nil, sdkaccess.ErrInvalidCredential
+}
+
+// decide returns the webhook verdict for candidate, from the cache when it is still fresh.
+func (p *provider) decide(ctx context.Context, candidate sdkaccess.Credential, now time.Time) (*sdkaccess.Result, error) {
+	cacheKey := hashCredential(candidate.Value)
+	p.mu.Lock()
+	cached, ok := p.cache[cacheKey]
+	p.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cloneResult(cached.result, candidate.Source), nil
+	}
+
+	result, err := p.call(ctx, candidate)
+	if err != nil {
+		return nil, err
+	}
+	ttl := p.allowTTL
+	if result == nil {
+		ttl = p.denyTTL
+	}
+	if ttl > 0 {
+		p.mu.Lock()
+		p.sweepLocked(now)
+		if len(p.cache) < maxCacheEntries {
+			p.cache[cacheKey] = decision{result: result, expires: now.Add(ttl)}
+		}
+		p.mu.Unlock()
+	}
+	return cloneResult(result, candidate.Source), nil
+}
+
+// sweepLocked drops expired decisions so revoked or rotated credentials do not pile up.
+func (p *provider) sweepLocked(now time.Time) {
+	if now.Sub(p.lastSweep) < cacheSweepInterval && len(p.cache) < maxCacheEntries {
+		return
+	}
+	p.lastSweep = now
+	for key, entry := range p.cache {
+		if !now.Before(entry.expires) {
+			delete(p.cache, key)
+		}
+	}
+}
+
+type webhookRequest struct {
+	Credential string `json:"credential"`
+	Source     string `json:"source"`
+}
+
+type webhookResponse struct {
+	Allowed          bool              `json:"allowed"`
+	Principal        string            `json:"principal"`
+	Tenant           string            `json:"tenant"`
+	AllowedModels    []string          `json:"allowed-models"`
+	DeniedModels     []string          `json:"denied-models"`
+	AllowedProviders []string          `json:"allowed-providers"`
+	Metadata         map[string]string `json:"metadata"`
+}
+
+// call posts candidate to the webhook. It returns a nil result when the webhook denies the
+// credential, and an error when no verdict could be obtained.
+func (p *provider) call(ctx context.Context, candidate sdkaccess.Credential) (*sdkaccess.Result, error) {
+	payload, err := json.Marshal(webhookRequest{Credential: candidate.Value, Source: candidate.Source})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for key, value := range p.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			log.Errorf("webhook access: close response body: %v", errClose)
+		}
+	}()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("POST %s: status %d", p.url, resp.StatusCode)
+	}
+	var verdict webhookResponse
+	if err = json.Unmarshal(body, &verdict); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	if !verdict.Allowed {
+		return nil, nil
+	}
+	principal := strings.TrimSpace(verdict.Principal)
+	if principal == "" {
+		// The credential is a secret; identify the caller by a short digest of it instead, which is
+		// stable across requests but safe to log, store and export.
+		principal = "webhook:" + hashCredential(candidate.Value)[:16]
+	}
+	metadata := make(map[string]string, len(verdict.Metadata)+4)
+	for key, value := range verdict.Metadata {
+		metadata[key] = value
+	}
+	if tenant := strings.TrimSpace(verdict.Tenant); tenant != "" {
+		metadata[sdkaccess.MetadataTenant] = tenant
+	}
+	for key, values := range map[string][]string{
+		sdkaccess.MetadataAllowedModels:    verdict.AllowedModels,
+		sdkaccess.MetadataDeniedModels:     verdict.DeniedModels,
+		sdkaccess.MetadataAllowedProviders: verdict.AllowedProviders,
+	} {
+		if len(values) > 0 {
+			metadata[key] = strings.Join(values, ",")
+		}
+	}
+	return &sdkaccess.Result{Provider: p.Identifier(), Principal: principal, Metadata: metadata}, nil
+}
+
+// cloneResult copies a cached result so callers can neither mutate the cache nor see the source of
+// the credential that populated it.
+func cloneResult(result *sdkaccess.Result, source string) *sdkaccess.Result {
+	if result == nil {
+		return nil
+	}
+	metadata := make(map[string]string, len(result.Metadata)+1)
+	for key, value := range result.Metadata {
+		metadata[key] = value
+	}
+	metadata["source"] = source
+	return &sdkaccess.Result{Provider: result.Provider, Principal: result.Principal, Metadata: metadata}
+}
+
+func hashCredential(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
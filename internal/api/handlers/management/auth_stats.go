@@ -0,0 +1,25 @@
+package management
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuthStats returns per-auth in-flight counts and latency moving averages.
+func (h *Handler) GetAuthStats(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"auths": h.authManager.LoadStats()})
+}
+
+// GetCircuitBreakers returns the circuit breaker state of every auth and its models.
+func (h *Handler) GetCircuitBreakers(c *gin.Context) {
+	if h.authManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "core auth manager unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"auths": h.authManager.BreakerStates()})
+}
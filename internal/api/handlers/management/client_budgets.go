@@ -0,0 +1,68 @@
+package management
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// GetClientBudgets returns the budget, usage and remaining allowance of every client key with a budget.
+func (h *Handler) GetClientBudgets(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"budgets": usage.GetBudgetTracker().Statuses(time.Now())})
+}
+
+// PutClientBudget sets the budget of a client key. Keys listed only in api-keys get a client-keys entry.
+func (h *Handler) PutClientBudget(c *gin.Context) {
+	var body struct {
+		APIKey string                     `json:"api-key"`
+		Budget *sdkconfig.ClientKeyBudget `json:"budget"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil || body.Budget == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	key := strings.TrimSpace(body.APIKey)
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing api-key"})
+		return
+	}
+	if entry := h.cfg.LookupClientKey(key); entry != nil {
+		entry.Budget = *body.Budget
+		h.persist(c)
+		return
+	}
+	for _, existing := range h.cfg.APIKeys {
+		if existing == key {
+			h.cfg.ClientKeys = append(h.cfg.ClientKeys, sdkconfig.ClientKey{Key: key, Budget: *body.Budget})
+			h.persist(c)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+}
+
+// DeleteClientBudget removes the budget of a client key.
+func (h *Handler) DeleteClientBudget(c *gin.Context) {
+	entry := h.cfg.LookupClientKey(strings.TrimSpace(c.Query("api-key")))
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+		return
+	}
+	entry.Budget = sdkconfig.ClientKeyBudget{}
+	h.persist(c)
+}
+
+// ResetClientBudgetUsage clears the tokens and requests counted against a client key.
+func (h *Handler) ResetClientBudgetUsage(c *gin.Context) {
+	key := strings.TrimSpace(c.Query("api-key"))
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing api-key"})
+		return
+	}
+	usage.GetBudgetTracker().Reset(key)
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
@@ -0,0 +1,208 @@
+package management
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// clientKeySecretPrefix marks keys generated by the server.
+const clientKeySecretPrefix = "cpk-"
+
+type clientKeyView struct {
+	sdkconfig.ClientKey
+	LastUsedAt *time.Time `json:"last-used-at,omitempty"`
+	Expired    bool       `json:"expired"`
+}
+
+// clientKeyRequest carries the editable fields of a client key. Nil fields are left unchanged.
+type clientKeyRequest struct {
+	Name                 *string    `json:"name"`
+	Owner                *string    `json:"owner"`
+	Group                *string    `json:"group"`
+	Scopes               *[]string  `json:"scopes"`
+	ExpiresAt            *time.Time `json:"expires-at"`
+	ExpiresInDays        *int       `json:"expires-in-days"`
+	NoExpiry             bool       `json:"no-expiry"`
+	AllowedModels        *[]string  `json:"allowed-models"`
+	DeniedModels         *[]string  `json:"denied-models"`
+	AllowedProviders     *[]string  `json:"allowed-providers"`
+	RPM                  *int       `json:"rpm"`
+	MaxConcurrentStreams *int       `json:"max-concurrent-streams"`
+}
+
+// apply copies the request onto entry after validating it.
+func (r *clientKeyRequest) apply(entry *sdkconfig.ClientKey, now time.Time) error {
+	if r.Scopes != nil {
+		for _, scope := range *r.Scopes {
+			if !sdkconfig.ValidClientKeyScope(scope) {
+				return fmt.Errorf("unknown scope %q", scope)
+			}
+		}
+	}
+	switch {
+	case r.NoExpiry:
+		entry.ExpiresAt = nil
+	case r.ExpiresInDays != nil:
+		if *r.ExpiresInDays <= 0 {
+			return fmt.Errorf("expires-in-days must be positive")
+		}
+		expires := now.AddDate(0, 0, *r.ExpiresInDays).UTC().Truncate(time.Second)
+		entry.ExpiresAt = &expires
+	case r.ExpiresAt != nil:
+		if !r.ExpiresAt.After(now) {
+			return fmt.Errorf("expires-at must be in the future")
+		}
+		expires := r.ExpiresAt.UTC().Truncate(time.Second)
+		entry.ExpiresAt = &expires
+	}
+	if r.Name != nil {
+		entry.Name = strings.TrimSpace(*r.Name)
+	}
+	if r.Owner != nil {
+		entry.Owner = strings.TrimSpace(*r.Owner)
+	}
+	if r.Group != nil {
+		entry.Group = strings.TrimSpace(*r.Group)
+	}
+	if r.Scopes != nil {
+		entry.Scopes = *r.Scopes
+	}
+	if r.AllowedModels != nil {
+		entry.AllowedModels = *r.AllowedModels
+	}
+	if r.DeniedModels != nil {
+		entry.DeniedModels = *r.DeniedModels
+	}
+	if r.AllowedProviders != nil {
+		entry.AllowedProviders = *r.AllowedProviders
+	}
+	if r.RPM != nil {
+		entry.RPM = *r.RPM
+	}
+	if r.MaxConcurrentStreams != nil {
+		entry.MaxConcurrentStreams = *r.MaxConcurrentStreams
+	}
+	return nil
+}
+
+// GetClientKeys lists the client keys with their last use. Hashed keys reveal neither their secret
+// nor their hash.
+func (h *Handler) GetClientKeys(c *gin.Context) {
+	now := time.Now()
+	activity := access.GetKeyActivity()
+	views := make([]clientKeyView, 0, len(h.cfg.ClientKeys))
+	for _, entry := range h.cfg.ClientKeys {
+		view := clientKeyView{ClientKey: entry, Expired: entry.Expired(now)}
+		view.KeyHash = ""
+		if entry.ID != "" {
+			if at, ok := activity.LastUsed(entry.ID); ok {
+				view.LastUsedAt = &at
+			}
+		}
+		views = append(views, view)
+	}
+	c.JSON(http.StatusOK, gin.H{"client-keys": views})
+}
+
+// CreateClientKey generates a client key, stores only its hash and returns the secret once.
+func (h *Handler) CreateClientKey(c *gin.Context) {
+	var body clientKeyRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	now := time.Now()
+	created := now.UTC().Truncate(time.Second)
+	entry := sdkconfig.ClientKey{CreatedAt: &created}
+	if err := body.apply(&entry, now); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to generate key: %v", err)})
+		return
+	}
+	secret = clientKeySecretPrefix + secret
+	for entry.ID == "" || h.clientKeyByID(entry.ID) != nil {
+		id, errID := randomHex(6)
+		if errID != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to generate key id: %v", errID)})
+			return
+		}
+		entry.ID = "ck_" + id
+	}
+	entry.KeyHash = sdkconfig.HashClientKey(secret)
+	h.cfg.ClientKeys = append(h.cfg.ClientKeys, entry)
+	if !h.saveConfig(c) {
+		return
+	}
+	view := entry
+	view.KeyHash = ""
+	c.JSON(http.StatusCreated, gin.H{"id": entry.ID, "key": secret, "client-key": view})
+}
+
+// PatchClientKey updates the metadata and policy of the client key named by ?id=.
+func (h *Handler) PatchClientKey(c *gin.Context) {
+	entry := h.clientKeyByID(c.Query("id"))
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "client key not found"})
+		return
+	}
+	var body clientKeyRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+	updated := *entry
+	if err := body.apply(&updated, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	*entry = updated
+	h.persist(c)
+}
+
+// RevokeClientKey removes the client key named by ?id=; requests using it are rejected once the
+// config is reloaded.
+func (h *Handler) RevokeClientKey(c *gin.Context) {
+	id := strings.TrimSpace(c.Query("id"))
+	for i := range h.cfg.ClientKeys {
+		if id != "" && h.cfg.ClientKeys[i].ID == id {
+			h.cfg.ClientKeys = append(h.cfg.ClientKeys[:i], h.cfg.ClientKeys[i+1:]...)
+			access.GetKeyActivity().Forget(id)
+			h.persist(c)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "client key not found"})
+}
+
+func (h *Handler) clientKeyByID(id string) *sdkconfig.ClientKey {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil
+	}
+	for i := range h.cfg.ClientKeys {
+		if h.cfg.ClientKeys[i].ID == id {
+			return &h.cfg.ClientKeys[i]
+		}
+	}
+	return nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
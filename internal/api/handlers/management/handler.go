@@ -218,6 +218,15 @@ func (h *Handler) Middleware() gin.HandlerFunc {
 
 // persist saves the current in-memory config to disk.
 func (h *Handler) persist(c *gin.Context) bool {
+	if !h.saveConfig(c) {
+		return false
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	return true
+}
+
+// saveConfig writes the in-memory config to disk, answering with an error when it fails.
+func (h *Handler) saveConfig(c *gin.Context) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 	// Preserve comments when writing
@@ -225,7 +234,6 @@ func (h *Handler) persist(c *gin.Context) bool {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("failed to save config: %v", err)})
 		return false
 	}
-	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	return true
 }
 
@@ -1,7 +1,12 @@
 package management
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
@@ -18,3 +23,128 @@ func (h *Handler) GetUsageStatistics(c *gin.Context) {
 		"failed_requests": snapshot.FailureCount,
 	})
 }
+
+const (
+	defaultUsageRecordLimit = 500
+	maxUsageRecordLimit     = 10000
+)
+
+// GetUsageRecords returns stored usage records, newest first. It accepts from, to, client-key,
+// auth-id, provider, model and limit; the range defaults to the last 24 hours.
+func (h *Handler) GetUsageRecords(c *gin.Context) {
+	query, err := usageQuery(c, 24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.Limit = defaultUsageRecordLimit
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, errLimit := strconv.Atoi(raw)
+		if errLimit != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		query.Limit = min(limit, maxUsageRecordLimit)
+	}
+	records, err := usage.QueryRecords(c.Request.Context(), query)
+	if err != nil {
+		usageStorageError(c, err)
+		return
+	}
+	if records == nil {
+		records = []usage.StoredRecord{}
+	}
+	c.JSON(http.StatusOK, gin.H{"from": query.From, "to": query.To, "records": records})
+}
+
+// GetUsageSummary aggregates stored hourly rollups. Besides the filters of GetUsageRecords it accepts
+// interval (hour, day or total; default day) and group-by, a comma-separated list of client-key,
+// auth-id, provider and model. The range defaults to the last 7 days.
+func (h *Handler) GetUsageSummary(c *gin.Context) {
+	query, err := usageQuery(c, 7*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	interval := strings.ToLower(strings.TrimSpace(c.DefaultQuery("interval", usage.IntervalDay)))
+	switch interval {
+	case usage.IntervalHour, usage.IntervalDay, usage.IntervalTotal:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be hour, day or total"})
+		return
+	}
+	var groupBy []string
+	for _, item := range strings.Split(c.Query("group-by"), ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if !usage.ValidDimension(item) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unknown group-by dimension %q", item)})
+			return
+		}
+		groupBy = append(groupBy, item)
+	}
+	rollups, err := usage.QueryRollups(c.Request.Context(), query)
+	if err != nil {
+		usageStorageError(c, err)
+		return
+	}
+	buckets := usage.Summarize(rollups, interval, groupBy, time.Local)
+	if buckets == nil {
+		buckets = []usage.SummaryBucket{}
+	}
+	var totals usage.SummaryBucket
+	if all := usage.Summarize(rollups, usage.IntervalTotal, nil, time.Local); len(all) > 0 {
+		totals = all[0]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"from":     query.From,
+		"to":       query.To,
+		"interval": interval,
+		"group-by": groupBy,
+		"buckets":  buckets,
+		"totals":   totals,
+	})
+}
+
+// usageQuery reads the range and filters shared by the usage storage endpoints.
+func usageQuery(c *gin.Context, defaultRange time.Duration) (usage.Query, error) {
+	query := usage.Query{
+		APIKey:   strings.TrimSpace(c.Query("client-key")),
+		AuthID:   strings.TrimSpace(c.Query("auth-id")),
+		Provider: strings.TrimSpace(c.Query("provider")),
+		Model:    strings.TrimSpace(c.Query("model")),
+	}
+	var err error
+	if query.To, err = parseUsageTime(c.Query("to"), time.Now()); err != nil {
+		return query, fmt.Errorf("invalid to: %w", err)
+	}
+	if query.From, err = parseUsageTime(c.Query("from"), query.To.Add(-defaultRange)); err != nil {
+		return query, fmt.Errorf("invalid from: %w", err)
+	}
+	if !query.From.Before(query.To) {
+		return query, fmt.Errorf("from must be before to")
+	}
+	return query, nil
+}
+
+// parseUsageTime accepts RFC 3339 timestamps and local YYYY-MM-DD dates.
+func parseUsageTime(raw string, fallback time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return fallback, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", raw, time.Local)
+}
+
+func usageStorageError(c *gin.Context, err error) {
+	if errors.Is(err, usage.ErrStorageDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,94 @@
+// Package middleware provides HTTP middleware components for the CLI Proxy API server.
+// This file contains the client budget middleware that enforces per-key spend limits.
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+)
+
+// Response headers reporting the remaining budget of the calling client key.
+const (
+	HeaderBudgetDailyTokensRemaining     = "X-Budget-Daily-Tokens-Remaining"
+	HeaderBudgetMonthlyTokensRemaining   = "X-Budget-Monthly-Tokens-Remaining"
+	HeaderBudgetDailyRequestsRemaining   = "X-Budget-Daily-Requests-Remaining"
+	HeaderBudgetMonthlyRequestsRemaining = "X-Budget-Monthly-Requests-Remaining"
+)
+
+// ClientBudgetMiddleware rejects generation requests from client keys whose daily or monthly
+// budget is exhausted and reports the remaining budget in response headers. It must run after the
+// server's client key middleware, which identifies the client key. Listing and token counting
+// requests are free.
+func ClientBudgetMiddleware(tracker *usage.BudgetTracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isGenerationRequest(c.Request) {
+			c.Next()
+			return
+		}
+		now := time.Now()
+		status, limited, admitted := tracker.Admit(c.GetString("clientKey"), now)
+		if !limited {
+			c.Next()
+			return
+		}
+		setRemainingHeader(c, HeaderBudgetDailyTokensRemaining, status.DailyTokensRemaining)
+		setRemainingHeader(c, HeaderBudgetMonthlyTokensRemaining, status.MonthlyTokensRemaining)
+		setRemainingHeader(c, HeaderBudgetDailyRequestsRemaining, status.DailyRequestsRemaining)
+		setRemainingHeader(c, HeaderBudgetMonthlyRequestsRemaining, status.MonthlyRequestsRemaining)
+		if admitted {
+			c.Next()
+			return
+		}
+		if status.ResetAt != nil {
+			retryAfter := int(math.Ceil(status.ResetAt.Sub(now).Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		message := fmt.Sprintf("%s budget exhausted for this API key", strings.ReplaceAll(status.Exhausted, "-", " "))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, tooManyRequestsBody(c.Request.URL.Path, message))
+	}
+}
+
+// isGenerationRequest reports whether r asks for model output; listings and token counting do not.
+func isGenerationRequest(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	path := r.URL.Path
+	return !strings.HasSuffix(path, "/count_tokens") && !strings.HasSuffix(path, ":countTokens")
+}
+
+func setRemainingHeader(c *gin.Context, name string, remaining *int64) {
+	if remaining != nil {
+		c.Header(name, strconv.FormatInt(*remaining, 10))
+	}
+}
+
+// tooManyRequestsBody shapes a 429 error the way the API family of path reports quota errors.
+func tooManyRequestsBody(path, message string) gin.H {
+	switch {
+	case strings.HasPrefix(path, "/v1beta"):
+		return gin.H{"error": gin.H{
+			"code":    http.StatusTooManyRequests,
+			"message": message,
+			"status":  "RESOURCE_EXHAUSTED",
+		}}
+	case strings.HasPrefix(path, "/v1/messages"):
+		return gin.H{"type": "error", "error": gin.H{
+			"type":    "rate_limit_error",
+			"message": message,
+		}}
+	default:
+		return gin.H{"error": gin.H{
+			"message": message,
+			"type":    "insufficient_quota",
+			"code":    "budget_exceeded",
+		}}
+	}
+}
@@ -0,0 +1,126 @@
+// Package middleware provides HTTP middleware components for the CLI Proxy API server.
+// This file contains the middleware that resolves client IPs behind trusted reverse proxies.
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultTrustedProxies are trusted when trusted-proxies is unset: a reverse proxy on the same host.
+var defaultTrustedProxies = []string{"127.0.0.1", "::1"}
+
+// TrustedProxies rewrites the remote address of requests relayed by a trusted reverse proxy to the
+// client address from X-Forwarded-For or X-Real-IP, so c.ClientIP() reports the real client. The
+// gin engine must trust no proxy itself. The proxy list can be replaced while requests are served.
+type TrustedProxies struct {
+	nets atomic.Pointer[[]*net.IPNet]
+}
+
+// NewTrustedProxies constructs the middleware trusting the default loopback proxies.
+func NewTrustedProxies() *TrustedProxies {
+	t := &TrustedProxies{}
+	_ = t.Set(nil)
+	return t
+}
+
+// Set replaces the trusted proxies with IPs or CIDRs; an empty list trusts loopback only. Invalid
+// entries leave the current list in place.
+func (t *TrustedProxies) Set(entries []string) error {
+	if t == nil {
+		return nil
+	}
+	if len(entries) == 0 {
+		entries = defaultTrustedProxies
+	}
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		nets = append(nets, network)
+	}
+	t.nets.Store(&nets)
+	return nil
+}
+
+// Middleware returns the gin middleware resolving client IPs.
+func (t *TrustedProxies) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t.resolve(c.Request)
+		c.Next()
+	}
+}
+
+func (t *TrustedProxies) resolve(r *http.Request) {
+	host, port, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
+	if err != nil || !t.trusted(net.ParseIP(host)) {
+		return
+	}
+	if client := t.forwardedClient(r.Header.Get("X-Forwarded-For")); client != "" {
+		r.RemoteAddr = net.JoinHostPort(client, port)
+		return
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		r.RemoteAddr = net.JoinHostPort(ip.String(), port)
+	}
+}
+
+// forwardedClient walks X-Forwarded-For from the nearest hop and returns the first address not
+// belonging to a trusted proxy, or the farthest one when every hop is trusted.
+func (t *TrustedProxies) forwardedClient(header string) string {
+	if header == "" {
+		return ""
+	}
+	hops := strings.Split(header, ",")
+	client := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		client = ip.String()
+		if !t.trusted(ip) {
+			break
+		}
+	}
+	return client
+}
+
+func (t *TrustedProxies) trusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	nets := t.nets.Load()
+	if nets == nil {
+		return false
+	}
+	for _, network := range *nets {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+// Package middleware provides HTTP middleware components for the CLI Proxy API server.
+// This file contains the client limits middleware that throttles client keys and client IPs.
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// clientLimitIdle is how long an unused scope is kept; after a minute its RPM bucket is full again,
+// so dropping it loses nothing.
+const clientLimitIdle = time.Minute
+
+type keyLimits struct {
+	rpm        int
+	maxStreams int
+}
+
+type clientScope struct {
+	capacity float64
+	tokens   float64
+	last     time.Time
+	seen     time.Time
+	inFlight int
+}
+
+// refill tops up the RPM bucket of the scope, resetting it when the limit changed.
+func (s *clientScope) refill(rpm int, now time.Time) {
+	capacity := float64(rpm)
+	if s.capacity != capacity {
+		s.capacity = capacity
+		s.tokens = capacity
+		s.last = now
+		return
+	}
+	if elapsed := now.Sub(s.last).Seconds(); elapsed > 0 {
+		s.tokens = math.Min(capacity, s.tokens+elapsed*capacity/60)
+		s.last = now
+	}
+}
+
+// ClientLimiter enforces requests-per-minute and concurrent-stream limits per client API key and
+// per client IP address.
+type ClientLimiter struct {
+	mu        sync.Mutex
+	defaults  config.ClientLimits
+	keys      map[string]keyLimits
+	scopes    map[string]*clientScope
+	lastSweep time.Time
+}
+
+// NewClientLimiter constructs a limiter without limits.
+func NewClientLimiter() *ClientLimiter {
+	return &ClientLimiter{keys: make(map[string]keyLimits), scopes: make(map[string]*clientScope)}
+}
+
+// SetLimits replaces the default limits and the per-key overrides. In-flight requests stay counted.
+func (l *ClientLimiter) SetLimits(defaults config.ClientLimits, clientKeys []sdkconfig.ClientKey) {
+	if l == nil {
+		return
+	}
+	keys := make(map[string]keyLimits, len(clientKeys))
+	for i := range clientKeys {
+		entry := clientKeys[i]
+		principal := entry.Principal()
+		if principal == "" || (entry.RPM <= 0 && entry.MaxConcurrentStreams <= 0) {
+			continue
+		}
+		keys[principal] = keyLimits{rpm: entry.RPM, maxStreams: entry.MaxConcurrentStreams}
+	}
+	l.mu.Lock()
+	l.defaults = defaults
+	l.keys = keys
+	l.mu.Unlock()
+}
+
+type scopeLimit struct {
+	id         string
+	label      string
+	rpm        int
+	maxStreams int
+}
+
+// scopesLocked lists the limits of a request. clientKey is set for requests authenticated by a
+// client key, which may override the default limits; principal identifies other callers, which
+// share the defaults in a namespace of their own.
+func (l *ClientLimiter) scopesLocked(clientKey, principal, ip string) []scopeLimit {
+	var limits []scopeLimit
+	rpm, maxStreams := l.defaults.RPM, l.defaults.MaxConcurrentStreams
+	switch {
+	case clientKey != "":
+		if override, ok := l.keys[clientKey]; ok {
+			if override.rpm > 0 {
+				rpm = override.rpm
+			}
+			if override.maxStreams > 0 {
+				maxStreams = override.maxStreams
+			}
+		}
+		if rpm > 0 || maxStreams > 0 {
+			limits = append(limits, scopeLimit{id: "key|" + clientKey, label: "API key", rpm: rpm, maxStreams: maxStreams})
+		}
+	case principal != "":
+		if rpm > 0 || maxStreams > 0 {
+			limits = append(limits, scopeLimit{id: "principal|" + principal, label: "API key", rpm: rpm, maxStreams: maxStreams})
+		}
+	}
+	if ip != "" && (l.defaults.PerIPRPM > 0 || l.defaults.PerIPMaxConcurrentStreams > 0) {
+		limits = append(limits, scopeLimit{id: "ip|" + ip, label: "client IP", rpm: l.defaults.PerIPRPM, maxStreams: l.defaults.PerIPMaxConcurrentStreams})
+	}
+	return limits
+}
+
+// acquire admits a request from the client key or principal and ip. On success it returns the
+// release function for the concurrent-stream slots; otherwise it returns why the request was
+// refused and when to retry.
+func (l *ClientLimiter) acquire(clientKey, principal, ip string, now time.Time) (release func(), reason string, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweepLocked(now)
+	limits := l.scopesLocked(clientKey, principal, ip)
+	if len(limits) == 0 {
+		return func() {}, "", 0
+	}
+	scopes := make([]*clientScope, len(limits))
+	for i, limit := range limits {
+		scope, ok := l.scopes[limit.id]
+		if !ok {
+			scope = &clientScope{}
+			l.scopes[limit.id] = scope
+		}
+		scope.seen = now
+		if limit.rpm > 0 {
+			scope.refill(limit.rpm, now)
+			if scope.tokens < 1 {
+				wait := time.Duration((1 - scope.tokens) / (scope.capacity / 60) * float64(time.Second))
+				return nil, fmt.Sprintf("%s request rate limit of %d per minute exceeded", limit.label, limit.rpm), wait
+			}
+		}
+		if limit.maxStreams > 0 && scope.inFlight >= limit.maxStreams {
+			return nil, fmt.Sprintf("%s concurrent stream limit of %d reached", limit.label, limit.maxStreams), time.Second
+		}
+		scopes[i] = scope
+	}
+	for i, limit := range limits {
+		if limit.rpm > 0 {
+			scopes[i].tokens--
+		}
+		scopes[i].inFlight++
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			for _, scope := range scopes {
+				scope.inFlight--
+			}
+			l.mu.Unlock()
+		})
+	}, "", 0
+}
+
+// sweepLocked drops idle scopes so per-IP state does not grow without bound.
+func (l *ClientLimiter) sweepLocked(now time.Time) {
+	if now.Sub(l.lastSweep) < clientLimitIdle {
+		return
+	}
+	l.lastSweep = now
+	for id, scope := range l.scopes {
+		if scope.inFlight == 0 && now.Sub(scope.seen) > clientLimitIdle {
+			delete(l.scopes, id)
+		}
+	}
+}
+
+// Middleware returns the Gin middleware enforcing the limits. It must run after AuthMiddleware and
+// the server's client key middleware, which identify the caller.
+func (l *ClientLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil || !isGenerationRequest(c.Request) {
+			c.Next()
+			return
+		}
+		var principal string
+		if apiKey := c.GetString("apiKey"); apiKey != "" {
+			principal = c.GetString("accessProvider") + "|" + apiKey
+		}
+		release, reason, retryAfter := l.acquire(c.GetString("clientKey"), principal, c.ClientIP(), time.Now())
+		if reason != "" {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, tooManyRequestsBody(c.Request.URL.Path, reason))
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
@@ -0,0 +1,82 @@
+// Package middleware provides HTTP middleware components for the CLI Proxy API server.
+// This file contains the client scope middleware that restricts keys to API families.
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// ClientScopeMiddleware rejects requests outside the scopes the authenticated principal was granted.
+// It must run after AuthMiddleware, which stores the scopes in the access metadata. Model listings
+// are open to every scope.
+func ClientScopeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := c.Get("accessMetadata")
+		if !ok {
+			c.Next()
+			return
+		}
+		metadata, _ := raw.(map[string]string)
+		granted := metadata[sdkaccess.MetadataScopes]
+		scope := requestScope(c.Request)
+		if granted == "" || scope == "" {
+			c.Next()
+			return
+		}
+		for _, item := range strings.Split(granted, ",") {
+			if strings.TrimSpace(item) == scope {
+				c.Next()
+				return
+			}
+		}
+		message := fmt.Sprintf("this API key is not allowed to use the %s API", scope)
+		c.AbortWithStatusJSON(http.StatusForbidden, forbiddenBody(c.Request.URL.Path, message))
+	}
+}
+
+// requestScope maps a request to the API family it belongs to, or "" for model listings.
+func requestScope(r *http.Request) string {
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/v1beta/"):
+		if r.Method == http.MethodGet && path == "/v1beta/models" {
+			return ""
+		}
+		return sdkconfig.ClientKeyScopeGemini
+	case strings.HasPrefix(path, "/v1/messages"):
+		return sdkconfig.ClientKeyScopeClaude
+	case path == "/v1/models":
+		return ""
+	default:
+		return sdkconfig.ClientKeyScopeOpenAI
+	}
+}
+
+// forbiddenBody shapes a 403 error the way the API family of path reports permission errors.
+func forbiddenBody(path, message string) gin.H {
+	switch {
+	case strings.HasPrefix(path, "/v1beta"):
+		return gin.H{"error": gin.H{
+			"code":    http.StatusForbidden,
+			"message": message,
+			"status":  "PERMISSION_DENIED",
+		}}
+	case strings.HasPrefix(path, "/v1/messages"):
+		return gin.H{"type": "error", "error": gin.H{
+			"type":    "permission_error",
+			"message": message,
+		}}
+	default:
+		return gin.H{"error": gin.H{
+			"message": message,
+			"type":    "invalid_request_error",
+			"code":    "scope_not_allowed",
+		}}
+	}
+}
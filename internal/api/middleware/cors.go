@@ -0,0 +1,179 @@
+// Package middleware provides HTTP middleware components for the CLI Proxy API server.
+// This file contains the CORS middleware that decides which browser origins may call the proxy.
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+const (
+	managementPathPrefix = "/v0/management"
+	defaultCORSMethods   = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+	legacyCORSMethods    = "GET, POST, PUT, DELETE, OPTIONS"
+)
+
+// corsPolicy is a CORSPolicy prepared for matching.
+type corsPolicy struct {
+	origins     []string
+	anyOrigin   bool
+	methods     string
+	headers     string
+	credentials bool
+	maxAge      string
+}
+
+// CORS applies the configured CORS policies. Policies can be replaced while requests are served.
+type CORS struct {
+	api        atomic.Pointer[corsPolicy]
+	management atomic.Pointer[corsPolicy]
+}
+
+// NewCORS constructs a CORS middleware that allows every origin until SetConfig is called.
+func NewCORS() *CORS {
+	c := &CORS{}
+	c.SetConfig(config.CORSConfig{})
+	return c
+}
+
+// SetConfig replaces the API and management policies.
+func (c *CORS) SetConfig(cfg config.CORSConfig) {
+	if c == nil {
+		return
+	}
+	api := newCORSPolicy(cfg.CORSPolicy)
+	if len(cfg.AllowedOrigins) == 0 {
+		// Without a configured policy keep the historic behaviour of allowing everything.
+		api = &corsPolicy{origins: []string{"*"}, anyOrigin: true, methods: legacyCORSMethods, headers: "*"}
+	}
+	management := api
+	if cfg.Management != nil {
+		management = newCORSPolicy(*cfg.Management)
+	}
+	c.api.Store(api)
+	c.management.Store(management)
+}
+
+func newCORSPolicy(cfg config.CORSPolicy) *corsPolicy {
+	policy := &corsPolicy{methods: defaultCORSMethods, credentials: cfg.AllowCredentials}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+		if origin == "" {
+			continue
+		}
+		if origin == "*" {
+			policy.anyOrigin = true
+		}
+		policy.origins = append(policy.origins, origin)
+	}
+	if methods := joinNonEmpty(cfg.AllowedMethods, true); methods != "" {
+		policy.methods = methods
+	}
+	policy.headers = joinNonEmpty(cfg.AllowedHeaders, false)
+	if policy.headers == "" {
+		policy.headers = "*"
+	}
+	if cfg.MaxAgeSeconds > 0 {
+		policy.maxAge = strconv.Itoa(cfg.MaxAgeSeconds)
+	}
+	return policy
+}
+
+// Middleware returns the gin handler. Preflight requests are answered directly.
+func (c *CORS) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		policy := c.api.Load()
+		if strings.HasPrefix(ctx.Request.URL.Path, managementPathPrefix) {
+			policy = c.management.Load()
+		}
+		policy.apply(ctx.Writer.Header(), ctx.Request)
+
+		if ctx.Request.Method == http.MethodOptions {
+			ctx.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// apply writes the CORS response headers for r. Origins outside the policy get none, so browsers
+// refuse to hand them the response.
+func (p *corsPolicy) apply(header http.Header, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if p.anyOrigin && !p.credentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Add("Vary", "Origin")
+		if origin == "" || !p.allows(origin) {
+			return
+		}
+		header.Set("Access-Control-Allow-Origin", origin)
+		if p.credentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+	header.Set("Access-Control-Allow-Methods", p.methods)
+	headers := p.headers
+	if headers == "*" && p.credentials {
+		// Browsers take "*" literally for credentialed requests, so echo what was asked for.
+		headers = r.Header.Get("Access-Control-Request-Headers")
+	}
+	if headers != "" {
+		header.Set("Access-Control-Allow-Headers", headers)
+	}
+	if p.maxAge != "" && r.Method == http.MethodOptions {
+		header.Set("Access-Control-Max-Age", p.maxAge)
+	}
+}
+
+func (p *corsPolicy) allows(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range p.origins {
+		if matchWildcard(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcard reports whether value matches pattern, where "*" matches any sequence of characters.
+func matchWildcard(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(value, part)
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(part):]
+	}
+	return len(value) >= len(last) && strings.HasSuffix(value, last)
+}
+
+func joinNonEmpty(values []string, upper bool) string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if upper {
+			value = strings.ToUpper(value)
+		}
+		out = append(out, value)
+	}
+	return strings.Join(out, ", ")
+}
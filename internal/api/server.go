@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -24,6 +25,7 @@ import (
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/logging"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/managementasset"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -116,6 +118,9 @@ type Server struct {
 	// server is the underlying HTTP server.
 	server *http.Server
 
+	// tlsCerts serves the TLS certificates when HTTPS is enabled and reloads them on change.
+	tlsCerts atomic.Pointer[tlsReloader]
+
 	// handlers contains the API handlers for processing requests.
 	handlers *handlers.BaseAPIHandler
 
@@ -129,6 +134,15 @@ type Server struct {
 	// accessManager handles request authentication providers.
 	accessManager *sdkaccess.Manager
 
+	// clientLimiter throttles client API keys and client IPs.
+	clientLimiter *middleware.ClientLimiter
+
+	// cors applies the CORS policies of the API and the management API.
+	cors *middleware.CORS
+
+	// trustedProxies resolves client IPs forwarded by trusted reverse proxies.
+	trustedProxies *middleware.TrustedProxies
+
 	// requestLogger is the request logger instance for dynamic configuration updates.
 	requestLogger logging.RequestLogger
 	loggerToggle  func(bool)
@@ -189,11 +203,20 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 
 	// Create gin engine
 	engine := gin.New()
+	// Client IPs drive per-IP limits and the management localhost check, so forwarded headers are
+	// only honoured from trusted proxies. The middleware resolves them and can be reconfigured on
+	// reload; gin itself trusts no proxy.
+	_ = engine.SetTrustedProxies(nil)
+	trustedProxies := middleware.NewTrustedProxies()
+	if errProxies := trustedProxies.Set(cfg.TrustedProxies); errProxies != nil {
+		log.Errorf("invalid trusted-proxies, trusting loopback only: %v", errProxies)
+	}
 	if optionState.engineConfigurator != nil {
 		optionState.engineConfigurator(engine)
 	}
 
 	// Add middleware
+	engine.Use(trustedProxies.Middleware())
 	engine.Use(logging.GinLogrusLogger())
 	engine.Use(logging.GinLogrusRecovery())
 	for _, mw := range optionState.extraMiddleware {
@@ -214,7 +237,9 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 		}
 	}
 
-	engine.Use(corsMiddleware())
+	cors := middleware.NewCORS()
+	cors.SetConfig(cfg.CORS)
+	engine.Use(cors.Middleware())
 	wd, err := os.Getwd()
 	if err != nil {
 		wd = configFilePath
@@ -240,6 +265,9 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 		currentPath:         wd,
 		envManagementSecret: envManagementSecret,
 		wsRoutes:            make(map[string]struct{}),
+		clientLimiter:       middleware.NewClientLimiter(),
+		cors:                cors,
+		trustedProxies:      trustedProxies,
 	}
 	s.wsAuthEnabled.Store(cfg.WebsocketAuth)
 	// Save initial YAML snapshot
@@ -247,6 +275,32 @@ func NewServer(cfg *config.Config, authManager *auth.Manager, accessManager *sdk
 	s.applyAccessConfig(nil, cfg)
 	managementasset.SetCurrentConfig(cfg)
 	auth.SetQuotaCooldownDisabled(cfg.DisableCooling)
+	applyRetryPolicy(authManager, cfg)
+	applyModelFallbacks(authManager, cfg)
+	applyRateLimits(authManager, cfg)
+	applyCircuitBreaker(authManager, cfg)
+	usage.SetClientKeys(cfg.APIKeys, cfg.ClientKeys)
+	usage.GetBudgetTracker().SetBudgets(cfg.ClientKeys)
+	s.clientLimiter.SetLimits(cfg.ClientLimits, cfg.ClientKeys)
+	if err = usage.ConfigurePricing(cfg.Pricing, filepath.Dir(configFilePath)); err != nil {
+		log.Errorf("failed to load pricing: %v", err)
+	}
+	if err = usage.ConfigureStorage(cfg.UsageStorage, filepath.Dir(configFilePath)); err != nil {
+		log.Errorf("failed to enable usage storage: %v", err)
+	}
+	if err = usage.ConfigureExport(cfg.UsageExport, filepath.Dir(configFilePath)); err != nil {
+		log.Errorf("failed to enable usage export: %v", err)
+	}
+	if cfg.AuthDir != "" {
+		access.GetKeyActivity().SetStatePath(filepath.Join(cfg.AuthDir, access.KeyActivityFileName))
+	}
+	// Leave a host-provided selector in place unless a strategy is configured explicitly.
+	if strings.TrimSpace(cfg.Routing.Strategy) != "" {
+		applyRoutingStrategy(authManager, cfg)
+	}
+	if authManager != nil {
+		authManager.SetConcurrencyWait(time.Duration(cfg.Routing.ConcurrencyWaitMS) * time.Millisecond)
+	}
 	// Initialize management handler
 	s.mgmt = managementHandlers.NewHandler(cfg, configFilePath, authManager)
 	if optionState.localPassword != "" {
@@ -297,7 +351,7 @@ func (s *Server) setupRoutes() {
 
 	// OpenAI compatible API routes
 	v1 := s.engine.Group("/v1")
-	v1.Use(AuthMiddleware(s.accessManager))
+	v1.Use(AuthMiddleware(s.accessManager), s.clientKeyMiddleware(), middleware.ClientScopeMiddleware(), s.clientLimiter.Middleware(), middleware.ClientBudgetMiddleware(usage.GetBudgetTracker()))
 	{
 		v1.GET("/models", s.unifiedModelsHandler(openaiHandlers, claudeCodeHandlers))
 		v1.POST("/chat/completions", openaiHandlers.ChatCompletions)
@@ -309,7 +363,7 @@ func (s *Server) setupRoutes() {
 
 	// Gemini compatible API routes
 	v1beta := s.engine.Group("/v1beta")
-	v1beta.Use(AuthMiddleware(s.accessManager))
+	v1beta.Use(AuthMiddleware(s.accessManager), s.clientKeyMiddleware(), middleware.ClientScopeMiddleware(), s.clientLimiter.Middleware(), middleware.ClientBudgetMiddleware(usage.GetBudgetTracker()))
 	{
 		v1beta.GET("/models", geminiHandlers.GeminiModels)
 		v1beta.POST("/models/:action", geminiHandlers.GeminiHandler)
@@ -328,6 +382,7 @@ func (s *Server) setupRoutes() {
 		})
 	})
 	s.engine.POST("/v1internal:method", geminiCLIHandlers.CLIHandler)
+	s.engine.GET("/metrics", s.serveMetrics)
 
 	// OAuth callback endpoints (reuse main server port)
 	// These endpoints receive provider redirects and persist
@@ -435,6 +490,8 @@ func (s *Server) registerManagementRoutes() {
 	mgmt.Use(s.managementAvailabilityMiddleware(), s.mgmt.Middleware())
 	{
 		mgmt.GET("/usage", s.mgmt.GetUsageStatistics)
+		mgmt.GET("/usage/records", s.mgmt.GetUsageRecords)
+		mgmt.GET("/usage/summary", s.mgmt.GetUsageSummary)
 		mgmt.GET("/config", s.mgmt.GetConfig)
 		mgmt.PUT("/config.yaml", s.mgmt.PutConfigYAML)
 		mgmt.GET("/config.yaml", s.mgmt.GetConfigFile)
@@ -469,6 +526,17 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.PATCH("/api-keys", s.mgmt.PatchAPIKeys)
 		mgmt.DELETE("/api-keys", s.mgmt.DeleteAPIKeys)
 
+		mgmt.GET("/client-keys", s.mgmt.GetClientKeys)
+		mgmt.POST("/client-keys", s.mgmt.CreateClientKey)
+		mgmt.PATCH("/client-keys", s.mgmt.PatchClientKey)
+		mgmt.DELETE("/client-keys", s.mgmt.RevokeClientKey)
+
+		mgmt.GET("/client-budgets", s.mgmt.GetClientBudgets)
+		mgmt.PUT("/client-budgets", s.mgmt.PutClientBudget)
+		mgmt.PATCH("/client-budgets", s.mgmt.PutClientBudget)
+		mgmt.DELETE("/client-budgets", s.mgmt.DeleteClientBudget)
+		mgmt.POST("/client-budgets/reset", s.mgmt.ResetClientBudgetUsage)
+
 		mgmt.GET("/generative-language-api-key", s.mgmt.GetGlKeys)
 		mgmt.PUT("/generative-language-api-key", s.mgmt.PutGlKeys)
 		mgmt.PATCH("/generative-language-api-key", s.mgmt.PatchGlKeys)
@@ -510,6 +578,8 @@ func (s *Server) registerManagementRoutes() {
 		mgmt.GET("/qwen-auth-url", s.mgmt.RequestQwenToken)
 		mgmt.GET("/iflow-auth-url", s.mgmt.RequestIFlowToken)
 		mgmt.GET("/get-auth-status", s.mgmt.GetAuthStatus)
+		mgmt.GET("/auth-stats", s.mgmt.GetAuthStats)
+		mgmt.GET("/circuit-breakers", s.mgmt.GetCircuitBreakers)
 	}
 }
 
@@ -523,6 +593,41 @@ func (s *Server) managementAvailabilityMiddleware() gin.HandlerFunc {
 	}
 }
 
+// clientKeyMiddleware stores the principal of requests authenticated by an inline API key provider
+// as "clientKey". Client-keys policies, groups, budgets and limit overrides are looked up by that
+// value only, so principals of other providers (JWT subjects, webhook principals, certificate
+// identities) cannot pick up the settings of a client key that happens to share the value.
+func (s *Server) clientKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg := s.cfg; cfg != nil && cfg.IsConfigAPIKeyProvider(c.GetString("accessProvider")) {
+			c.Set("clientKey", c.GetString("apiKey"))
+		}
+		c.Next()
+	}
+}
+
+// serveMetrics writes the Prometheus metrics when they are enabled, checking the bearer token when
+// one is configured.
+func (s *Server) serveMetrics(c *gin.Context) {
+	cfg := s.cfg
+	if cfg == nil || !cfg.Metrics.Enable {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+	if token := cfg.Metrics.BearerToken; token != "" {
+		provided := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	}
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	if _, err := metrics.Default().WriteTo(c.Writer); err != nil {
+		log.WithError(err).Debug("failed to write metrics")
+	}
+}
+
 func (s *Server) serveManagementControlPanel(c *gin.Context) {
 	cfg := s.cfg
 	if cfg == nil || cfg.RemoteManagement.DisableControlPanel {
@@ -653,6 +758,20 @@ func (s *Server) unifiedModelsHandler(openaiHandler *openai.OpenAIAPIHandler, cl
 // Returns:
 //   - error: An error if the server fails to start
 func (s *Server) Start() error {
+	if s.cfg != nil && s.cfg.TLS.Enable {
+		reloader, err := newTLSReloader(s.cfg.TLS)
+		if err != nil {
+			return fmt.Errorf("failed to start HTTPS server: %v", err)
+		}
+		s.tlsCerts.Store(reloader)
+		s.server.TLSConfig = reloader.serverConfig()
+		log.Debugf("Starting API server with TLS on %s", s.server.Addr)
+		if err = s.server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to start HTTPS server: %v", err)
+		}
+		return nil
+	}
+
 	log.Debugf("Starting API server on %s", s.server.Addr)
 
 	// Start the HTTP server.
@@ -663,6 +782,20 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// ReloadTLSCertificates re-reads the TLS certificate, key and client CA files so new connections
+// use them. On error the previously loaded certificates stay in use. It is a no-op without HTTPS.
+func (s *Server) ReloadTLSCertificates() {
+	reloader := s.tlsCerts.Load()
+	if reloader == nil {
+		return
+	}
+	if err := reloader.Reload(); err != nil {
+		log.Errorf("failed to reload TLS certificates, keeping the current ones: %v", err)
+		return
+	}
+	log.Info("TLS certificates reloaded")
+}
+
 // Stop gracefully shuts down the API server without interrupting any
 // active connections.
 //
@@ -690,24 +823,98 @@ func (s *Server) Stop(ctx context.Context) error {
 	return nil
 }
 
-// corsMiddleware returns a Gin middleware handler that adds CORS headers
-// to every response, allowing cross-origin requests.
-//
-// Returns:
-//   - gin.HandlerFunc: The CORS middleware handler
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "*")
+// applyRetryPolicy translates request-retry settings into the core manager retry policy.
+func applyRetryPolicy(manager *auth.Manager, cfg *config.Config) {
+	if manager == nil || cfg == nil {
+		return
+	}
+	policy := auth.RetryPolicy{
+		MaxRetries:     cfg.RequestRetry,
+		InitialBackoff: time.Duration(cfg.RetryPolicy.InitialBackoffMS) * time.Millisecond,
+		MaxBackoff:     time.Duration(cfg.RetryPolicy.MaxBackoffMS) * time.Millisecond,
+		StatusCodes:    append([]int(nil), cfg.RetryPolicy.StatusCodes...),
+	}
+	for i := range cfg.RetryPolicy.Providers {
+		entry := cfg.RetryPolicy.Providers[i]
+		provider := strings.ToLower(strings.TrimSpace(entry.Provider))
+		if provider == "" {
+			continue
+		}
+		if policy.Providers == nil {
+			policy.Providers = make(map[string]auth.ProviderRetryPolicy)
+		}
+		policy.Providers[provider] = auth.ProviderRetryPolicy{
+			MaxRetries:  entry.RequestRetry,
+			StatusCodes: append([]int(nil), entry.StatusCodes...),
+		}
+	}
+	manager.SetRetryPolicy(policy)
+}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
+// applyRoutingStrategy installs the credential selector named by routing.strategy.
+func applyRoutingStrategy(manager *auth.Manager, cfg *config.Config) {
+	if manager == nil || cfg == nil {
+		return
+	}
+	selector, err := auth.NewSelector(cfg.Routing.Strategy)
+	if err != nil {
+		log.Warnf("%v, falling back to %s", err, auth.SelectionStrategyRoundRobin)
+	}
+	if affinity, ok := selector.(*auth.AffinitySelector); ok && cfg.Routing.AffinityTTLSeconds > 0 {
+		affinity.TTL = time.Duration(cfg.Routing.AffinityTTLSeconds) * time.Second
+	}
+	manager.SetSelector(selector)
+}
+
+// applyModelFallbacks translates model-fallbacks entries into the core manager fallback chains.
+func applyModelFallbacks(manager *auth.Manager, cfg *config.Config) {
+	if manager == nil || cfg == nil {
+		return
+	}
+	chains := make(map[string][]string, len(cfg.ModelFallbacks))
+	for i := range cfg.ModelFallbacks {
+		entry := cfg.ModelFallbacks[i]
+		model := strings.TrimSpace(entry.Model)
+		if model == "" {
+			continue
 		}
+		chains[model] = append(chains[model], entry.Fallbacks...)
+	}
+	manager.SetModelFallbacks(chains)
+}
 
-		c.Next()
+// applyRateLimits translates rate-limits entries into the core manager rate limit rules.
+func applyRateLimits(manager *auth.Manager, cfg *config.Config) {
+	if manager == nil || cfg == nil {
+		return
 	}
+	rules := make([]auth.RateLimitRule, 0, len(cfg.RateLimits))
+	for _, entry := range cfg.RateLimits {
+		rules = append(rules, auth.RateLimitRule{
+			Provider: entry.Provider,
+			Model:    entry.Model,
+			RPM:      entry.RPM,
+			TPM:      entry.TPM,
+		})
+	}
+	manager.SetRateLimits(rules)
+}
+
+// applyCircuitBreaker translates circuit-breaker settings into the core manager breaker policy.
+func applyCircuitBreaker(manager *auth.Manager, cfg *config.Config) {
+	if manager == nil || cfg == nil {
+		return
+	}
+	breaker := cfg.CircuitBreaker
+	manager.SetBreakerPolicy(auth.BreakerPolicy{
+		Disabled:         breaker.Disable,
+		FailureThreshold: breaker.FailureThreshold,
+		ErrorRate:        breaker.ErrorRate,
+		MinRequests:      breaker.MinRequests,
+		Window:           time.Duration(breaker.WindowSeconds) * time.Second,
+		Cooldown:         time.Duration(breaker.CooldownSeconds) * time.Second,
+		MaxCooldown:      time.Duration(breaker.MaxCooldownSeconds) * time.Second,
+	})
 }
 
 func (s *Server) applyAccessConfig(oldCfg, newCfg *config.Config) {
@@ -776,6 +983,95 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 		}
 	}
 
+	if oldCfg == nil || oldCfg.RequestRetry != cfg.RequestRetry || !reflect.DeepEqual(oldCfg.RetryPolicy, cfg.RetryPolicy) {
+		applyRetryPolicy(s.handlers.AuthManager, cfg)
+		if oldCfg != nil {
+			log.Debugf("request retry policy updated (request-retry %d -> %d)", oldCfg.RequestRetry, cfg.RequestRetry)
+		}
+	}
+
+	if oldCfg == nil {
+		if strings.TrimSpace(cfg.Routing.Strategy) != "" {
+			applyRoutingStrategy(s.handlers.AuthManager, cfg)
+		}
+	} else if oldCfg.Routing.Strategy != cfg.Routing.Strategy || oldCfg.Routing.AffinityTTLSeconds != cfg.Routing.AffinityTTLSeconds {
+		applyRoutingStrategy(s.handlers.AuthManager, cfg)
+		log.Debugf("routing strategy updated from %s to %s", auth.NormalizeSelectionStrategy(oldCfg.Routing.Strategy), auth.NormalizeSelectionStrategy(cfg.Routing.Strategy))
+	}
+	if (oldCfg == nil || oldCfg.Routing.ConcurrencyWaitMS != cfg.Routing.ConcurrencyWaitMS) && s.handlers.AuthManager != nil {
+		s.handlers.AuthManager.SetConcurrencyWait(time.Duration(cfg.Routing.ConcurrencyWaitMS) * time.Millisecond)
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.ModelFallbacks, cfg.ModelFallbacks) {
+		applyModelFallbacks(s.handlers.AuthManager, cfg)
+		if oldCfg != nil {
+			log.Debugf("model fallbacks updated (%d -> %d chains)", len(oldCfg.ModelFallbacks), len(cfg.ModelFallbacks))
+		}
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.RateLimits, cfg.RateLimits) {
+		applyRateLimits(s.handlers.AuthManager, cfg)
+		if oldCfg != nil {
+			log.Debugf("rate limits updated (%d -> %d rules)", len(oldCfg.RateLimits), len(cfg.RateLimits))
+		}
+	}
+
+	if oldCfg == nil || oldCfg.CircuitBreaker != cfg.CircuitBreaker {
+		applyCircuitBreaker(s.handlers.AuthManager, cfg)
+		if oldCfg != nil {
+			log.Debug("circuit breaker policy updated")
+		}
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.APIKeys, cfg.APIKeys) || !reflect.DeepEqual(oldCfg.ClientKeys, cfg.ClientKeys) {
+		usage.SetClientKeys(cfg.APIKeys, cfg.ClientKeys)
+	}
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.ClientKeys, cfg.ClientKeys) {
+		usage.GetBudgetTracker().SetBudgets(cfg.ClientKeys)
+	}
+	if oldCfg == nil || oldCfg.ClientLimits != cfg.ClientLimits || !reflect.DeepEqual(oldCfg.ClientKeys, cfg.ClientKeys) {
+		s.clientLimiter.SetLimits(cfg.ClientLimits, cfg.ClientKeys)
+		if oldCfg != nil {
+			log.Debug("client limits updated")
+		}
+	}
+
+	// The price file may have changed on its own, so it is re-read on every reload.
+	if err := usage.ConfigurePricing(cfg.Pricing, filepath.Dir(s.configFilePath)); err != nil {
+		log.Errorf("failed to reload pricing: %v", err)
+	}
+
+	if oldCfg == nil || oldCfg.UsageStorage != cfg.UsageStorage {
+		if err := usage.ConfigureStorage(cfg.UsageStorage, filepath.Dir(s.configFilePath)); err != nil {
+			log.Errorf("failed to update usage storage: %v", err)
+		} else if oldCfg != nil {
+			log.Debug("usage storage updated")
+		}
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.UsageExport, cfg.UsageExport) {
+		if err := usage.ConfigureExport(cfg.UsageExport, filepath.Dir(s.configFilePath)); err != nil {
+			log.Errorf("failed to update usage export: %v", err)
+		} else if oldCfg != nil {
+			log.Debug("usage export updated")
+		}
+	}
+
+	if oldCfg != nil && !reflect.DeepEqual(oldCfg.TrustedProxies, cfg.TrustedProxies) {
+		if err := s.trustedProxies.Set(cfg.TrustedProxies); err != nil {
+			log.Errorf("invalid trusted-proxies, keeping the previous list: %v", err)
+		} else {
+			log.Debug("trusted proxies updated")
+		}
+	}
+
+	if oldCfg == nil || !reflect.DeepEqual(oldCfg.CORS, cfg.CORS) {
+		s.cors.SetConfig(cfg.CORS)
+		if oldCfg != nil {
+			log.Debug("CORS policy updated")
+		}
+	}
+
 	// Update log level dynamically when debug flag changes
 	if oldCfg == nil || oldCfg.Debug != cfg.Debug {
 		util.SetLogLevel(cfg)
@@ -848,6 +1144,9 @@ func (s *Server) UpdateClients(cfg *config.Config) {
 	// Count client sources from configuration and auth directory
 	authFiles := util.CountAuthFiles(cfg.AuthDir)
 	glAPIKeyCount := len(cfg.GlAPIKey)
+	for i := range cfg.GlAPIKeyGroups {
+		glAPIKeyCount += len(cfg.GlAPIKeyGroups[i].APIKeys)
+	}
 	claudeAPIKeyCount := len(cfg.ClaudeKey)
 	codexAPIKeyCount := len(cfg.CodexKey)
 	openAICompatCount := 0
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// tlsReloader serves the certificate and client CA files configured at startup and swaps them in
+// place when Reload is called, so renewed certificates apply to new connections without a restart.
+type tlsReloader struct {
+	cfg     config.TLSConfig
+	current atomic.Pointer[tls.Config]
+}
+
+// newTLSReloader loads the files named by cfg once; later reloads keep the last good state on error.
+func newTLSReloader(cfg config.TLSConfig) (*tlsReloader, error) {
+	r := &tlsReloader{cfg: cfg}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads the certificate, key and client CA files.
+func (r *tlsReloader) Reload() error {
+	tlsConfig, err := buildTLSConfig(r.cfg)
+	if err != nil {
+		return err
+	}
+	r.current.Store(tlsConfig)
+	return nil
+}
+
+// serverConfig returns the listener configuration, which resolves every handshake against the
+// most recently loaded files.
+func (r *tlsReloader) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}
+}
+
+// buildTLSConfig loads the server certificate and, when a client CA is configured, the pool used to
+// verify client certificates.
+func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	if strings.TrimSpace(cfg.CertFile) == "" || strings.TrimSpace(cfg.KeyFile) == "" {
+		return nil, fmt.Errorf("tls: cert-file and key-file are required")
+	}
+	certificate, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: load certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{certificate},
+	}
+	if strings.TrimSpace(cfg.ClientCAFile) == "" {
+		return tlsConfig, nil
+	}
+	data, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("tls: read client-ca-file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("tls: client-ca-file %s contains no certificates", cfg.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	switch strings.ToLower(strings.TrimSpace(cfg.ClientAuth)) {
+	case "", config.TLSClientAuthRequire:
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	case config.TLSClientAuthVerifyIfGiven:
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("tls: unknown client-auth %q", cfg.ClientAuth)
+	}
+	return tlsConfig, nil
+}
@@ -22,6 +22,13 @@ type Config struct {
 	// Port is the network port on which the API server will listen.
 	Port int `yaml:"port" json:"-"`
 
+	// TLS serves the API over HTTPS, optionally requiring client certificates.
+	TLS TLSConfig `yaml:"tls,omitempty" json:"-"`
+
+	// TrustedProxies lists the IPs and CIDRs of reverse proxies whose X-Forwarded-For and X-Real-IP
+	// headers are trusted for the client IP. Empty trusts loopback proxies only.
+	TrustedProxies []string `yaml:"trusted-proxies,omitempty" json:"-"`
+
 	// AuthDir is the directory where authentication token files are stored.
 	AuthDir string `yaml:"auth-dir" json:"-"`
 
@@ -34,6 +41,15 @@ type Config struct {
 	// UsageStatisticsEnabled toggles in-memory usage aggregation; when false, usage data is discarded.
 	UsageStatisticsEnabled bool `yaml:"usage-statistics-enabled" json:"usage-statistics-enabled"`
 
+	// UsageStorage persists usage records and bounds the in-memory statistics.
+	UsageStorage UsageStorage `yaml:"usage-storage,omitempty" json:"usage-storage,omitempty"`
+
+	// UsageExport ships usage records to an HTTP collector.
+	UsageExport UsageExport `yaml:"usage-export,omitempty" json:"usage-export,omitempty"`
+
+	// Pricing sets the model prices used to compute the cost of usage.
+	Pricing PricingConfig `yaml:"pricing,omitempty" json:"pricing,omitempty"`
+
 	// DisableCooling disables quota cooldown scheduling when true.
 	DisableCooling bool `yaml:"disable-cooling" json:"disable-cooling"`
 
@@ -46,9 +62,36 @@ type Config struct {
 	// GlAPIKey is the API key for the generative language API.
 	GlAPIKey []string `yaml:"generative-language-api-key" json:"generative-language-api-key"`
 
+	// GlAPIKeyGroups lists generative language API keys that serve a credential group.
+	GlAPIKeyGroups []GlAPIKeyGroup `yaml:"generative-language-api-key-groups,omitempty" json:"generative-language-api-key-groups,omitempty"`
+
 	// RequestRetry defines the retry times when the request failed.
 	RequestRetry int `yaml:"request-retry" json:"request-retry"`
 
+	// RetryPolicy tunes backoff timing, retriable statuses and per-provider overrides for request-retry.
+	RetryPolicy RetryPolicy `yaml:"retry-policy" json:"retry-policy"`
+
+	// Routing selects how a credential is chosen among those able to serve a request.
+	Routing RoutingConfig `yaml:"routing" json:"routing"`
+
+	// ModelFallbacks lists ordered fallback models tried when every credential for a model fails.
+	ModelFallbacks []ModelFallback `yaml:"model-fallbacks,omitempty" json:"model-fallbacks,omitempty"`
+
+	// RateLimits caps requests and input tokens per minute for every credential of a provider.
+	RateLimits []RateLimit `yaml:"rate-limits,omitempty" json:"rate-limits,omitempty"`
+
+	// CircuitBreaker takes credentials out of rotation after repeated upstream failures.
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuit-breaker" json:"circuit-breaker"`
+
+	// ClientLimits throttles client API keys and client IPs before requests reach the credential pool.
+	ClientLimits ClientLimits `yaml:"client-limits" json:"client-limits"`
+
+	// CORS controls which browser origins may call the API and the management API.
+	CORS CORSConfig `yaml:"cors,omitempty" json:"cors,omitempty"`
+
+	// Metrics exposes Prometheus metrics at /metrics.
+	Metrics MetricsConfig `yaml:"metrics,omitempty" json:"metrics,omitempty"`
+
 	// ClaudeKey defines a list of Claude API key configurations as specified in the YAML configuration file.
 	ClaudeKey []ClaudeKey `yaml:"claude-api-key" json:"claude-api-key"`
 
@@ -82,6 +125,286 @@ type QuotaExceeded struct {
 	SwitchPreviewModel bool `yaml:"switch-preview-model" json:"switch-preview-model"`
 }
 
+// RetryPolicy configures how failed upstream requests are retried on the same credential.
+type RetryPolicy struct {
+	// InitialBackoffMS is the delay before the first retry in milliseconds; later retries double it.
+	InitialBackoffMS int `yaml:"initial-backoff-ms" json:"initial-backoff-ms"`
+
+	// MaxBackoffMS caps the retry delay in milliseconds. Upstream Retry-After hints above it skip the retry.
+	MaxBackoffMS int `yaml:"max-backoff-ms" json:"max-backoff-ms"`
+
+	// StatusCodes lists the upstream HTTP statuses that trigger a retry.
+	// When empty, 403, 408, 429, 500, 502, 503 and 504 are retried.
+	StatusCodes []int `yaml:"status-codes,omitempty" json:"status-codes,omitempty"`
+
+	// Providers overrides the retry count and statuses for individual providers.
+	Providers []ProviderRetryPolicy `yaml:"providers,omitempty" json:"providers,omitempty"`
+}
+
+// ProviderRetryPolicy overrides retry behaviour for a single provider (e.g. "claude", "gemini-cli").
+type ProviderRetryPolicy struct {
+	// Provider is the provider identifier the override applies to.
+	Provider string `yaml:"provider" json:"provider"`
+
+	// RequestRetry overrides the global request-retry count when set.
+	RequestRetry *int `yaml:"request-retry,omitempty" json:"request-retry,omitempty"`
+
+	// StatusCodes overrides the retriable statuses when non-empty.
+	StatusCodes []int `yaml:"status-codes,omitempty" json:"status-codes,omitempty"`
+}
+
+// RoutingConfig controls credential selection.
+type RoutingConfig struct {
+	// Strategy is one of "round-robin" (default), "priority", "weighted", "fill-first", "latency" or "affinity".
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+
+	// AffinityTTLSeconds is how long an idle conversation stays pinned under the affinity strategy.
+	// Zero uses the default of 1800 seconds.
+	AffinityTTLSeconds int `yaml:"affinity-ttl-seconds,omitempty" json:"affinity-ttl-seconds,omitempty"`
+
+	// ConcurrencyWaitMS is how long a request queues when every credential is at its max-concurrency
+	// or out of rpm/tpm budget. Zero uses the default of 30000; a negative value fails such requests immediately.
+	ConcurrencyWaitMS int `yaml:"concurrency-wait-ms,omitempty" json:"concurrency-wait-ms,omitempty"`
+}
+
+// ModelFallback defines the fallback chain for a single requested model.
+type ModelFallback struct {
+	// Model is the client-facing model name the chain applies to.
+	Model string `yaml:"model" json:"model"`
+
+	// Fallbacks are tried in order, possibly on other providers, when Model cannot be served.
+	Fallbacks []string `yaml:"fallbacks" json:"fallbacks"`
+}
+
+// CircuitBreakerConfig tunes the per-credential and per-credential-per-model circuit breakers.
+// Only transport errors, timeouts and 5xx responses count as failures.
+type CircuitBreakerConfig struct {
+	// Disable turns circuit breaking off.
+	Disable bool `yaml:"disable,omitempty" json:"disable,omitempty"`
+
+	// FailureThreshold opens a breaker after this many consecutive failures. Zero uses 5.
+	FailureThreshold int `yaml:"failure-threshold,omitempty" json:"failure-threshold,omitempty"`
+
+	// ErrorRate opens a breaker when the failure ratio within the window reaches it, e.g. 0.5.
+	// Zero disables the error-rate check.
+	ErrorRate float64 `yaml:"error-rate,omitempty" json:"error-rate,omitempty"`
+
+	// MinRequests is how many outcomes the window needs before ErrorRate applies. Zero uses 20.
+	MinRequests int `yaml:"min-requests,omitempty" json:"min-requests,omitempty"`
+
+	// WindowSeconds is the error-rate window length. Zero uses 60.
+	WindowSeconds int `yaml:"window-seconds,omitempty" json:"window-seconds,omitempty"`
+
+	// CooldownSeconds is how long a breaker stays open before a half-open probe. Zero uses 30.
+	// Each failed probe doubles it.
+	CooldownSeconds int `yaml:"cooldown-seconds,omitempty" json:"cooldown-seconds,omitempty"`
+
+	// MaxCooldownSeconds caps the doubled cooldown. Zero uses 600.
+	MaxCooldownSeconds int `yaml:"max-cooldown-seconds,omitempty" json:"max-cooldown-seconds,omitempty"`
+}
+
+// TLSConfig configures HTTPS on the API listener.
+type TLSConfig struct {
+	// Enable serves HTTPS instead of plain HTTP.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// CertFile is the PEM server certificate chain file. It is reloaded when it changes on disk.
+	CertFile string `yaml:"cert-file" json:"cert-file"`
+
+	// KeyFile is the PEM private key file of CertFile. It is reloaded when it changes on disk.
+	KeyFile string `yaml:"key-file" json:"key-file"`
+
+	// ClientCAFile is a PEM bundle of CAs that sign client certificates. Setting it enables mutual TLS.
+	ClientCAFile string `yaml:"client-ca-file,omitempty" json:"client-ca-file,omitempty"`
+
+	// ClientAuth is "require" (default) to reject connections without a valid client certificate, or
+	// "verify-if-given" to verify certificates only when clients present one.
+	ClientAuth string `yaml:"client-auth,omitempty" json:"client-auth,omitempty"`
+}
+
+// TLS client authentication modes.
+const (
+	TLSClientAuthRequire       = "require"
+	TLSClientAuthVerifyIfGiven = "verify-if-given"
+)
+
+// ClientLimits caps how fast and how wide clients may use the proxy. Zero values are unlimited.
+// Only generation requests count; model listings and token counting are not limited.
+type ClientLimits struct {
+	// RPM caps requests per minute per client API key. client-keys entries may override it.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// MaxConcurrentStreams caps simultaneous in-flight requests per client API key, streaming or not.
+	// client-keys entries may override it.
+	MaxConcurrentStreams int `yaml:"max-concurrent-streams,omitempty" json:"max-concurrent-streams,omitempty"`
+
+	// PerIPRPM caps requests per minute per client IP address.
+	PerIPRPM int `yaml:"per-ip-rpm,omitempty" json:"per-ip-rpm,omitempty"`
+
+	// PerIPMaxConcurrentStreams caps simultaneous in-flight requests per client IP address.
+	PerIPMaxConcurrentStreams int `yaml:"per-ip-max-concurrent-streams,omitempty" json:"per-ip-max-concurrent-streams,omitempty"`
+}
+
+// UsageStorage configures where usage records are persisted and how long they are kept.
+type UsageStorage struct {
+	// Type is "file" for JSONL files or "postgres" when the Postgres-backed store is in use.
+	// Empty keeps usage in memory only.
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+
+	// Dir is the directory of the file storage. Relative paths resolve against the config file
+	// directory; empty uses "usage" there, or under WRITABLE_PATH when set.
+	Dir string `yaml:"dir,omitempty" json:"dir,omitempty"`
+
+	// RetentionDays is how long individual records are kept. Zero uses 30.
+	RetentionDays int `yaml:"retention-days,omitempty" json:"retention-days,omitempty"`
+
+	// RollupRetentionDays is how long hourly rollups are kept. Zero uses 365.
+	RollupRetentionDays int `yaml:"rollup-retention-days,omitempty" json:"rollup-retention-days,omitempty"`
+
+	// MaxDetailsPerModel caps the request details kept in memory per API key and model, with or
+	// without storage. Zero uses 1000.
+	MaxDetailsPerModel int `yaml:"max-details-per-model,omitempty" json:"max-details-per-model,omitempty"`
+}
+
+// Usage storage types.
+const (
+	UsageStorageFile     = "file"
+	UsageStoragePostgres = "postgres"
+)
+
+// UsageExport configures the plugin that POSTs usage records in batches to a collector.
+type UsageExport struct {
+	// URL is the collector endpoint. Empty disables the export.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+
+	// Format is "json" for a JSON array per batch or "ndjson" for one record per line. Empty uses "json".
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+
+	// Headers are added to every request, e.g. an Authorization header of the collector.
+	Headers map[string]string `yaml:"headers,omitempty" json:"-"`
+
+	// BatchSize is the maximum number of records per request. Zero uses 100.
+	BatchSize int `yaml:"batch-size,omitempty" json:"batch-size,omitempty"`
+
+	// FlushIntervalSeconds is how often partial batches are sent. Zero uses 10.
+	FlushIntervalSeconds int `yaml:"flush-interval-seconds,omitempty" json:"flush-interval-seconds,omitempty"`
+
+	// TimeoutSeconds bounds each request. Zero uses 10.
+	TimeoutSeconds int `yaml:"timeout-seconds,omitempty" json:"timeout-seconds,omitempty"`
+
+	// MaxRetries is how often a failed request is retried before its batch is buffered on disk.
+	// Zero uses 3; negative disables retries.
+	MaxRetries int `yaml:"max-retries,omitempty" json:"max-retries,omitempty"`
+
+	// HMACSecret, when set, signs every request body with HMAC-SHA256.
+	HMACSecret string `yaml:"hmac-secret,omitempty" json:"-"`
+
+	// BufferDir keeps batches the collector did not accept. Relative paths resolve against the config
+	// file directory; empty uses "usage-export" there, or under WRITABLE_PATH when set.
+	BufferDir string `yaml:"buffer-dir,omitempty" json:"buffer-dir,omitempty"`
+
+	// MaxBufferMB caps the disk buffer; the oldest batches are dropped beyond it. Zero uses 100.
+	MaxBufferMB int `yaml:"max-buffer-mb,omitempty" json:"max-buffer-mb,omitempty"`
+}
+
+// Usage export formats.
+const (
+	UsageExportJSON   = "json"
+	UsageExportNDJSON = "ndjson"
+)
+
+// PricingConfig holds the price table used for cost accounting.
+type PricingConfig struct {
+	// File is a YAML file with a "models" list of prices. Relative paths resolve against the config
+	// file directory. It is re-read whenever the config reloads.
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+
+	// Models lists prices that override entries of the file for the same model.
+	Models []ModelPrice `yaml:"models,omitempty" json:"models,omitempty"`
+}
+
+// ModelPrice is the list price of a model in US dollars per million tokens.
+type ModelPrice struct {
+	// Model is the upstream model name; "*" matches any run of characters, e.g. "claude-sonnet-4*".
+	// Exact names take precedence over patterns.
+	Model string `yaml:"model" json:"model"`
+
+	// Input is the price of uncached input tokens.
+	Input float64 `yaml:"input,omitempty" json:"input,omitempty"`
+
+	// Output is the price of output tokens.
+	Output float64 `yaml:"output,omitempty" json:"output,omitempty"`
+
+	// CachedInput is the price of input tokens served from the prompt cache. Zero uses Input.
+	CachedInput float64 `yaml:"cached-input,omitempty" json:"cached-input,omitempty"`
+
+	// Reasoning is the price of reasoning tokens. Zero uses Output.
+	Reasoning float64 `yaml:"reasoning,omitempty" json:"reasoning,omitempty"`
+}
+
+// CORSPolicy describes the cross-origin requests browsers may make.
+type CORSPolicy struct {
+	// AllowedOrigins lists the origins allowed to read responses, e.g. "https://tools.example.com".
+	// "*" matches any sequence of characters, so "https://*.example.com" allows every subdomain.
+	AllowedOrigins []string `yaml:"allowed-origins,omitempty" json:"allowed-origins,omitempty"`
+
+	// AllowedMethods lists the methods allowed in preflight requests. Empty allows
+	// GET, POST, PUT, PATCH, DELETE and OPTIONS.
+	AllowedMethods []string `yaml:"allowed-methods,omitempty" json:"allowed-methods,omitempty"`
+
+	// AllowedHeaders lists the request headers allowed in preflight requests. Empty allows any.
+	AllowedHeaders []string `yaml:"allowed-headers,omitempty" json:"allowed-headers,omitempty"`
+
+	// AllowCredentials lets browsers send cookies and HTTP authentication with cross-origin requests.
+	AllowCredentials bool `yaml:"allow-credentials,omitempty" json:"allow-credentials,omitempty"`
+
+	// MaxAgeSeconds is how long browsers may cache a preflight response. Zero leaves it to the browser.
+	MaxAgeSeconds int `yaml:"max-age-seconds,omitempty" json:"max-age-seconds,omitempty"`
+}
+
+// CORSConfig holds the CORS policy of the API and, optionally, a separate one for the management API.
+// Without allowed-origins every origin may call the API.
+type CORSConfig struct {
+	CORSPolicy `yaml:",inline"`
+
+	// Management replaces the policy for /v0/management. When unset the API policy applies; when set
+	// without allowed-origins, browsers may not call the management API from other origins.
+	Management *CORSPolicy `yaml:"management,omitempty" json:"management,omitempty"`
+}
+
+// MetricsConfig controls the Prometheus scrape endpoint.
+type MetricsConfig struct {
+	// Enable serves the metrics at /metrics.
+	Enable bool `yaml:"enable" json:"enable"`
+
+	// BearerToken, when set, must be sent by scrapers as "Authorization: Bearer <token>".
+	BearerToken string `yaml:"bearer-token,omitempty" json:"-"`
+}
+
+// RateLimit defines per-credential request and token budgets for a provider.
+type RateLimit struct {
+	// Provider is the provider identifier, e.g. "claude", "codex" or an openai-compatibility name.
+	Provider string `yaml:"provider" json:"provider"`
+
+	// Model limits a single model with its own budget. Empty applies the budget across all models.
+	Model string `yaml:"model,omitempty" json:"model,omitempty"`
+
+	// RPM is the requests per minute allowed per credential; 0 means unlimited.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM is the estimated input tokens per minute allowed per credential; 0 means unlimited.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+}
+
+// GlAPIKeyGroup assigns generative language API keys to credential groups.
+type GlAPIKeyGroup struct {
+	// Group lists the comma-separated credential groups served by APIKeys.
+	Group string `yaml:"group" json:"group"`
+
+	// APIKeys are the generative language API keys of the group.
+	APIKeys []string `yaml:"api-keys" json:"api-keys"`
+}
+
 // ClaudeKey represents the configuration for a Claude API key,
 // including the API key itself and an optional base URL for the API endpoint.
 type ClaudeKey struct {
@@ -97,6 +420,24 @@ type ClaudeKey struct {
 
 	// Models defines upstream model names and aliases for request routing.
 	Models []ClaudeModel `yaml:"models" json:"models"`
+	// Priority ranks this credential for the priority and fill-first routing strategies; higher wins.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Weight sets this credential's traffic share for the weighted routing strategy (default 1).
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps simultaneous upstream requests on this credential; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// RPM caps requests per minute on this credential; 0 means unlimited.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM caps estimated input tokens per minute on this credential; 0 means unlimited.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+
+	// Group assigns this credential to comma-separated credential groups; only client keys of a
+	// group are served by it. Empty serves clients without a group.
+	Group string `yaml:"group,omitempty" json:"group,omitempty"`
 }
 
 // ClaudeModel describes a mapping between an alias and the actual upstream model name.
@@ -120,6 +461,25 @@ type CodexKey struct {
 
 	// ProxyURL overrides the global proxy setting for this API key if provided.
 	ProxyURL string `yaml:"proxy-url" json:"proxy-url"`
+
+	// Priority ranks this credential for the priority and fill-first routing strategies; higher wins.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Weight sets this credential's traffic share for the weighted routing strategy (default 1).
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps simultaneous upstream requests on this credential; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// RPM caps requests per minute on this credential; 0 means unlimited.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM caps estimated input tokens per minute on this credential; 0 means unlimited.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+
+	// Group assigns this credential to comma-separated credential groups; only client keys of a
+	// group are served by it. Empty serves clients without a group.
+	Group string `yaml:"group,omitempty" json:"group,omitempty"`
 }
 
 // OpenAICompatibility represents the configuration for OpenAI API compatibility
@@ -140,6 +500,23 @@ type OpenAICompatibility struct {
 
 	// Models defines the model configurations including aliases for routing.
 	Models []OpenAICompatibilityModel `yaml:"models" json:"models"`
+	// Priority ranks every key of this provider for the priority and fill-first routing strategies.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Weight sets the traffic share of every key of this provider for the weighted routing strategy.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency caps simultaneous upstream requests on every key of this provider; 0 means unlimited.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// RPM caps requests per minute on every key of this provider; 0 means unlimited.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM caps estimated input tokens per minute on every key of this provider; 0 means unlimited.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+
+	// Group assigns every key of this provider to comma-separated credential groups.
+	Group string `yaml:"group,omitempty" json:"group,omitempty"`
 }
 
 // OpenAICompatibilityAPIKey represents an API key configuration with optional proxy setting.
@@ -149,6 +526,23 @@ type OpenAICompatibilityAPIKey struct {
 
 	// ProxyURL overrides the global proxy setting for this API key if provided.
 	ProxyURL string `yaml:"proxy-url,omitempty" json:"proxy-url,omitempty"`
+	// Priority overrides the provider-level priority for this key when non-zero.
+	Priority int `yaml:"priority,omitempty" json:"priority,omitempty"`
+
+	// Weight overrides the provider-level weight for this key when non-zero.
+	Weight int `yaml:"weight,omitempty" json:"weight,omitempty"`
+
+	// MaxConcurrency overrides the provider-level concurrency cap for this key when non-zero.
+	MaxConcurrency int `yaml:"max-concurrency,omitempty" json:"max-concurrency,omitempty"`
+
+	// RPM overrides the provider-level requests-per-minute cap for this key when non-zero.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// TPM overrides the provider-level tokens-per-minute cap for this key when non-zero.
+	TPM int `yaml:"tpm,omitempty" json:"tpm,omitempty"`
+
+	// Group overrides the provider-level credential groups for this key when non-empty.
+	Group string `yaml:"group,omitempty" json:"group,omitempty"`
 }
 
 // OpenAICompatibilityModel represents a model configuration for OpenAI compatibility,
@@ -285,7 +679,7 @@ func syncInlineAccessProvider(cfg *Config) {
 			cfg.APIKeys = append([]string(nil), provider.APIKeys...)
 		}
 	}
-	cfg.Access.Providers = nil
+	cfg.RemoveConfigAPIKeyProviders()
 }
 
 // looksLikeBcrypt returns true if the provided string appears to be a bcrypt hash.
@@ -0,0 +1,212 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
+	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+)
+
+var (
+	ttfbBuckets     = []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30}
+	durationBuckets = []float64{0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+)
+
+func init() {
+	coreusage.RegisterPlugin(defaultCollector)
+}
+
+// SessionCounter reports the number of connected websocket relay sessions.
+type SessionCounter interface {
+	SessionCount() int
+}
+
+// AuthLister lists the credentials whose availability is reported, such as a coreauth.Manager.
+type AuthLister interface {
+	List() []*coreauth.Auth
+}
+
+// Collector gathers the proxy metrics. Counters and histograms are fed by usage records and auth
+// manager hooks; availability gauges are computed from the auth lister when the metrics are written.
+type Collector struct {
+	requests       *counterVec
+	tokens         *counterVec
+	upstreamErrors *counterVec
+	refreshes      *counterVec
+	ttfb           *histogramVec
+	duration       *histogramVec
+
+	auths    atomic.Pointer[AuthLister]
+	sessions atomic.Pointer[SessionCounter]
+}
+
+var defaultCollector = NewCollector()
+
+// Default returns the process-wide collector, which is registered as a usage plugin.
+func Default() *Collector { return defaultCollector }
+
+// NewCollector creates an empty collector.
+func NewCollector() *Collector {
+	return &Collector{
+		requests: newCounterVec("cliproxy_requests_total",
+			"Requests served, by provider, model and outcome.", "provider", "model", "outcome"),
+		tokens: newCounterVec("cliproxy_tokens_total",
+			"Tokens reported by upstream responses, by provider, model and token type.", "provider", "model", "type"),
+		upstreamErrors: newCounterVec("cliproxy_upstream_errors_total",
+			"Failed upstream attempts, by provider, model and HTTP status.", "provider", "model", "status"),
+		refreshes: newCounterVec("cliproxy_auth_refresh_total",
+			"Credential refresh attempts, by credential, provider and outcome.", "auth_id", "provider", "outcome"),
+		ttfb: newHistogramVec("cliproxy_upstream_ttfb_seconds",
+			"Time until the first upstream response payload of successful attempts.", ttfbBuckets, "provider", "model"),
+		duration: newHistogramVec("cliproxy_upstream_duration_seconds",
+			"Total upstream duration of successful attempts.", durationBuckets, "provider", "model"),
+	}
+}
+
+// SetSessionCounter sets the websocket relay whose sessions are reported; nil stops reporting.
+func (c *Collector) SetSessionCounter(counter SessionCounter) {
+	if counter == nil {
+		c.sessions.Store(nil)
+		return
+	}
+	c.sessions.Store(&counter)
+}
+
+// SetAuthLister sets the source of the credentials whose availability is reported; nil stops reporting.
+func (c *Collector) SetAuthLister(lister AuthLister) {
+	if lister == nil {
+		c.auths.Store(nil)
+		return
+	}
+	c.auths.Store(&lister)
+}
+
+// HandleUsage implements coreusage.Plugin.
+func (c *Collector) HandleUsage(_ context.Context, record coreusage.Record) {
+	outcome := "success"
+	if record.Failed {
+		outcome = "failure"
+	}
+	c.requests.add(1, record.Provider, record.Model, outcome)
+	c.tokens.add(float64(record.Detail.InputTokens), record.Provider, record.Model, "input")
+	c.tokens.add(float64(record.Detail.OutputTokens), record.Provider, record.Model, "output")
+	c.tokens.add(float64(record.Detail.ReasoningTokens), record.Provider, record.Model, "reasoning")
+	c.tokens.add(float64(record.Detail.CachedTokens), record.Provider, record.Model, "cached")
+}
+
+// OnAuthRegistered implements coreauth.Hook.
+func (c *Collector) OnAuthRegistered(context.Context, *coreauth.Auth) {}
+
+// OnAuthUpdated implements coreauth.Hook.
+func (c *Collector) OnAuthUpdated(context.Context, *coreauth.Auth) {}
+
+// OnResult implements coreauth.Hook.
+func (c *Collector) OnResult(_ context.Context, result coreauth.Result) {
+	if !result.Success {
+		status := "unknown"
+		if result.Error != nil && result.Error.HTTPStatus > 0 {
+			status = strconv.Itoa(result.Error.HTTPStatus)
+		}
+		c.upstreamErrors.add(1, result.Provider, result.Model, status)
+		return
+	}
+	if result.Latency <= 0 {
+		return
+	}
+	if result.FirstByte > 0 {
+		c.ttfb.observe(result.FirstByte.Seconds(), result.Provider, result.Model)
+	}
+	c.duration.observe(result.Latency.Seconds(), result.Provider, result.Model)
+}
+
+// OnRefresh implements coreauth.RefreshHook.
+func (c *Collector) OnRefresh(_ context.Context, auth *coreauth.Auth, err error) {
+	if auth == nil {
+		return
+	}
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	c.refreshes.add(1, auth.ID, auth.Provider, outcome)
+}
+
+// WriteTo writes every metric family in the text exposition format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	counting := &countingWriter{w: w}
+	buf := bufio.NewWriter(counting)
+	c.requests.write(buf)
+	c.tokens.write(buf)
+	c.upstreamErrors.write(buf)
+	c.ttfb.write(buf)
+	c.duration.write(buf)
+	c.refreshes.write(buf)
+	c.writeAuthGauges(buf, time.Now())
+	writeModelGauges(buf, registry.GetGlobalRegistry().GetModelAvailability())
+	if counter := c.sessions.Load(); counter != nil {
+		writeGauge(buf, "cliproxy_wsrelay_sessions", "Connected websocket relay sessions.", nil,
+			[]gaugeSample{{value: float64((*counter).SessionCount())}})
+	}
+	err := buf.Flush()
+	return counting.n, err
+}
+
+func (c *Collector) writeAuthGauges(w *bufio.Writer, now time.Time) {
+	var auths []*coreauth.Auth
+	if lister := c.auths.Load(); lister != nil {
+		auths = (*lister).List()
+	}
+	sort.Slice(auths, func(i, j int) bool { return auths[i].ID < auths[j].ID })
+
+	labels := []string{"auth_id", "provider"}
+	available := make([]gaugeSample, 0, len(auths))
+	cooldown := make([]gaugeSample, 0, len(auths))
+	for _, auth := range auths {
+		values := []string{auth.ID, auth.Provider}
+		remaining := 0.0
+		if auth.Unavailable && auth.NextRetryAfter.After(now) {
+			remaining = auth.NextRetryAfter.Sub(now).Seconds()
+		}
+		up := 0.0
+		if !auth.Disabled && auth.Status != coreauth.StatusDisabled && remaining == 0 {
+			up = 1
+		}
+		available = append(available, gaugeSample{values: values, value: up})
+		cooldown = append(cooldown, gaugeSample{values: values, value: remaining})
+	}
+	writeGauge(w, "cliproxy_auth_available",
+		"Whether the credential can currently be selected (1) or is disabled or cooling down (0).", labels, available)
+	writeGauge(w, "cliproxy_auth_cooldown_seconds",
+		"Seconds until a cooling down credential becomes available again.", labels, cooldown)
+}
+
+func writeModelGauges(w *bufio.Writer, models []registry.ModelAvailability) {
+	samples := make([]gaugeSample, 0, 3*len(models))
+	for _, model := range models {
+		samples = append(samples,
+			gaugeSample{values: []string{model.Model, "available"}, value: float64(model.Available)},
+			gaugeSample{values: []string{model.Model, "quota_exceeded"}, value: float64(model.QuotaExceeded)},
+			gaugeSample{values: []string{model.Model, "suspended"}, value: float64(model.Suspended)},
+		)
+	}
+	writeGauge(w, "cliproxy_model_clients",
+		"Registered clients per model, by availability state.", []string{"model", "state"}, samples)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -612,6 +612,40 @@ func (r *ModelRegistry) GetModelCount(modelID string) int {
 	return 0
 }
 
+// ModelAvailability summarises the clients registered for one model.
+type ModelAvailability struct {
+	// Model is the model ID.
+	Model string
+	// Available is the number of clients that can currently serve the model, as GetModelCount.
+	Available int
+	// QuotaExceeded is the number of clients still within their quota cooldown.
+	QuotaExceeded int
+	// Suspended is the number of clients temporarily removed from the model.
+	Suspended int
+}
+
+// GetModelAvailability returns the client availability of every registered model, ordered by model ID.
+func (r *ModelRegistry) GetModelAvailability() []ModelAvailability {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	now := time.Now()
+	quotaExpiredDuration := 5 * time.Minute
+	out := make([]ModelAvailability, 0, len(r.models))
+	for modelID, registration := range r.models {
+		entry := ModelAvailability{Model: modelID, Suspended: len(registration.SuspendedClients)}
+		for _, quotaTime := range registration.QuotaExceededClients {
+			if quotaTime != nil && now.Sub(*quotaTime) < quotaExpiredDuration {
+				entry.QuotaExceeded++
+			}
+		}
+		entry.Available = max(registration.Count-entry.QuotaExceeded-entry.Suspended, 0)
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Model < out[j].Model })
+	return out
+}
+
 // GetModelProviders returns provider identifiers that currently supply the given model
 // Parameters:
 //   - modelID: The model ID to check
@@ -178,7 +178,7 @@ func (e *GeminiCLIExecutor) Execute(ctx context.Context, auth *cliproxyauth.Auth
 			continue
 		}
 
-		err = statusErr{code: httpResp.StatusCode, msg: string(data)}
+		err = statusErr{code: httpResp.StatusCode, msg: string(data), retryAfter: parseRetryAfter(httpResp.Header)}
 		return resp, err
 	}
 
@@ -301,12 +301,12 @@ func (e *GeminiCLIExecutor) ExecuteStream(ctx context.Context, auth *cliproxyaut
 				}
 				continue
 			}
-			err = statusErr{code: httpResp.StatusCode, msg: string(data)}
+			err = statusErr{code: httpResp.StatusCode, msg: string(data), retryAfter: parseRetryAfter(httpResp.Header)}
 			return nil, err
 		}
 
 		out := make(chan cliproxyexecutor.StreamChunk)
-		stream = out
+		stream = reporter.trackStream(ctx, opts, out)
 		go func(resp *http.Response, reqBody []byte, attempt string) {
 			defer close(out)
 			defer func() {
@@ -1,13 +1,39 @@
 package executor
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	sdktranslator "github.com/router-for-me/CLIProxyAPI/v6/sdk/translator"
 	"github.com/tidwall/gjson"
 	"github.com/tiktoken-go/tokenizer"
 )
 
+// EstimateInputTokens approximates the input tokens of a client request by translating it to the
+// OpenAI chat format and counting it locally. It falls back to four bytes per token on failure.
+func EstimateInputTokens(model string, opts cliproxyexecutor.Options) int64 {
+	payload := opts.OriginalRequest
+	if len(payload) == 0 {
+		return 0
+	}
+	fallback := int64(len(payload) / 4)
+	translated := payload
+	if to := sdktranslator.FromString("openai"); opts.SourceFormat != to {
+		translated = sdktranslator.TranslateRequest(opts.SourceFormat, to, model, bytes.Clone(payload), opts.Stream)
+	}
+	enc, err := tokenizerForModel(model)
+	if err != nil {
+		return fallback
+	}
+	count, err := countOpenAIChatTokens(enc, translated)
+	if err != nil || count == 0 {
+		return fallback
+	}
+	return count
+}
+
 // tokenizerForModel returns a tokenizer codec suitable for an OpenAI-style model id.
 func tokenizerForModel(model string) (tokenizer.Codec, error) {
 	sanitized := strings.ToLower(strings.TrimSpace(model))
@@ -6,15 +6,21 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
 	cliproxyauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
 	"github.com/tidwall/gjson"
 )
 
+// maxPartialCompletionText caps the forwarded text kept per stream for partial usage estimates;
+// content beyond it is estimated at four bytes per token.
+const maxPartialCompletionText = 4 << 20
+
 type usageReporter struct {
 	provider    string
 	model       string
@@ -23,6 +29,14 @@ type usageReporter struct {
 	source      string
 	requestedAt time.Time
 	once        sync.Once
+	published   atomic.Bool
+
+	// Stream state kept by trackStream for estimating the usage of streams cut short.
+	streamMu       sync.Mutex
+	streaming      bool
+	streamFailed   bool
+	completion     strings.Builder
+	completionRest int64
 }
 
 func newUsageReporter(ctx context.Context, provider, model string, auth *cliproxyauth.Auth) *usageReporter {
@@ -61,6 +75,9 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 	if r == nil {
 		return
 	}
+	if failed && r.deferStreamFailure() {
+		return
+	}
 	if detail.TotalTokens == 0 {
 		total := detail.InputTokens + detail.OutputTokens + detail.ReasoningTokens
 		if total > 0 {
@@ -70,7 +87,12 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 	if detail.InputTokens == 0 && detail.OutputTokens == 0 && detail.ReasoningTokens == 0 && detail.CachedTokens == 0 && detail.TotalTokens == 0 && !failed {
 		return
 	}
+	r.publishRecord(ctx, detail, failed, false)
+}
+
+func (r *usageReporter) publishRecord(ctx context.Context, detail usage.Detail, failed, partial bool) {
 	r.once.Do(func() {
+		r.published.Store(true)
 		usage.PublishRecord(ctx, usage.Record{
 			Provider:    r.provider,
 			Model:       r.model,
@@ -79,11 +101,151 @@ func (r *usageReporter) publishWithOutcome(ctx context.Context, detail usage.Det
 			AuthID:      r.authID,
 			RequestedAt: r.requestedAt,
 			Failed:      failed,
+			Partial:     partial,
 			Detail:      detail,
 		})
 	})
 }
 
+// trackStream forwards the chunks of in and remembers the content sent to the client. Usage only
+// arrives with the final provider chunk, so when the stream fails or the client goes away before
+// that, a failed record is published with the prompt counted by the local tokenizer and the
+// completion estimated from the forwarded content, flagged as partial.
+func (r *usageReporter) trackStream(ctx context.Context, opts cliproxyexecutor.Options, in <-chan cliproxyexecutor.StreamChunk) <-chan cliproxyexecutor.StreamChunk {
+	r.streamMu.Lock()
+	r.streaming = true
+	r.streamMu.Unlock()
+	out := make(chan cliproxyexecutor.StreamChunk)
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			select {
+			case out <- chunk:
+				if chunk.Err != nil {
+					r.deferStreamFailure()
+				} else {
+					r.observeStreamPayload(chunk.Payload)
+				}
+			case <-ctx.Done():
+				// The client stopped reading; let the producer finish once the upstream request is cancelled.
+				go func() {
+					for range in {
+					}
+				}()
+				r.finishStream(ctx, opts)
+				return
+			}
+		}
+		r.finishStream(ctx, opts)
+	}()
+	return out
+}
+
+// deferStreamFailure records that a tracked stream failed, leaving the record to finishStream. It
+// reports false when no stream is tracked.
+func (r *usageReporter) deferStreamFailure() bool {
+	r.streamMu.Lock()
+	defer r.streamMu.Unlock()
+	if !r.streaming {
+		return false
+	}
+	r.streamFailed = true
+	return true
+}
+
+func (r *usageReporter) observeStreamPayload(payload []byte) {
+	text := streamCompletionText(payload)
+	if text == "" {
+		return
+	}
+	r.streamMu.Lock()
+	defer r.streamMu.Unlock()
+	if room := maxPartialCompletionText - r.completion.Len(); room < len(text) {
+		r.completionRest += int64(len(text) - max(room, 0))
+		text = text[:max(room, 0)]
+	}
+	r.completion.WriteString(text)
+}
+
+// finishStream publishes the record of a stream that ended without provider usage.
+func (r *usageReporter) finishStream(ctx context.Context, opts cliproxyexecutor.Options) {
+	r.streamMu.Lock()
+	failed := r.streamFailed || ctx.Err() != nil
+	text := r.completion.String()
+	rest := r.completionRest
+	r.streamMu.Unlock()
+	if !failed || r.published.Load() {
+		return
+	}
+	if text == "" && rest == 0 {
+		// Nothing reached the client; keep the plain failure record.
+		r.publishRecord(ctx, usage.Detail{}, true, false)
+		return
+	}
+	detail := usage.Detail{
+		InputTokens:  EstimateInputTokens(r.model, opts),
+		OutputTokens: estimateTextTokens(r.model, text) + rest/4,
+	}
+	detail.TotalTokens = detail.InputTokens + detail.OutputTokens
+	r.publishRecord(ctx, detail, true, true)
+}
+
+// estimateTextTokens counts text with the local tokenizer, falling back to four bytes per token.
+func estimateTextTokens(model, text string) int64 {
+	if text == "" {
+		return 0
+	}
+	if enc, err := tokenizerForModel(model); err == nil {
+		if count, errCount := enc.Count(text); errCount == nil {
+			return int64(count)
+		}
+	}
+	return int64(len(text) / 4)
+}
+
+// streamCompletionKeys name the fields that carry generated content in the stream formats served to
+// clients: OpenAI chat and responses, Claude messages and Gemini.
+var streamCompletionKeys = map[string]bool{
+	"text":              true,
+	"content":           true,
+	"thinking":          true,
+	"reasoning_content": true,
+	"refusal":           true,
+	"arguments":         true,
+	"partial_json":      true,
+	"delta":             true,
+}
+
+// streamCompletionText extracts the generated text of a client stream chunk. Summary events that
+// repeat earlier deltas, such as the responses API "*.done" events, are skipped.
+func streamCompletionText(payload []byte) string {
+	var sb strings.Builder
+	for _, line := range bytes.Split(payload, []byte("\n")) {
+		data := jsonPayload(line)
+		if len(data) == 0 || !gjson.ValidBytes(data) {
+			continue
+		}
+		root := gjson.ParseBytes(data)
+		if eventType := root.Get("type").String(); strings.HasSuffix(eventType, ".done") || strings.HasSuffix(eventType, ".completed") {
+			continue
+		}
+		collectCompletionText(root, &sb)
+	}
+	return sb.String()
+}
+
+func collectCompletionText(node gjson.Result, sb *strings.Builder) {
+	node.ForEach(func(key, value gjson.Result) bool {
+		switch {
+		case value.Type == gjson.String && streamCompletionKeys[key.String()]:
+			sb.WriteString(value.String())
+		case value.IsObject() || value.IsArray():
+			collectCompletionText(value, sb)
+		}
+		return true
+	})
+}
+
 func apiKeyFromContext(ctx context.Context) string {
 	if ctx == nil {
 		return ""
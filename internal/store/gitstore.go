@@ -394,6 +394,57 @@ func (s *GitTokenStore) PersistAuthFiles(_ context.Context, message string, path
 	return s.commitAndPushLocked(message, filtered...)
 }
 
+// runtimeStatePath returns where runtime auth state is kept: inside the git directory of the
+// repository, so it stays local, is never committed and is never treated as a credential.
+func (s *GitTokenStore) runtimeStatePath() string {
+	repoDir := s.repoDirSnapshot()
+	if repoDir == "" {
+		return ""
+	}
+	return filepath.Join(repoDir, ".git", "cliproxy-runtime-state.json")
+}
+
+// LoadRuntimeState reads the runtime auth state from the local repository.
+func (s *GitTokenStore) LoadRuntimeState(_ context.Context) ([]byte, error) {
+	if err := s.EnsureRepository(); err != nil {
+		return nil, err
+	}
+	path := s.runtimeStatePath()
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git token store: read runtime state: %w", err)
+	}
+	return data, nil
+}
+
+// SaveRuntimeState writes the runtime auth state next to the local repository. The state changes
+// often and only matters to this instance, so it is neither committed nor pushed.
+func (s *GitTokenStore) SaveRuntimeState(_ context.Context, data []byte) error {
+	if err := s.EnsureRepository(); err != nil {
+		return err
+	}
+	path := s.runtimeStatePath()
+	if path == "" {
+		return fmt.Errorf("git token store: repository path not configured")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("git token store: write runtime state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("git token store: rename runtime state: %w", err)
+	}
+	return nil
+}
+
 func (s *GitTokenStore) resolveDeletePath(id string) (string, error) {
 	if strings.ContainsRune(id, os.PathSeparator) || filepath.IsAbs(id) {
 		return id, nil
@@ -23,8 +23,9 @@ import (
 )
 
 const (
-	objectStoreConfigKey  = "config/config.yaml"
-	objectStoreAuthPrefix = "auths"
+	objectStoreConfigKey       = "config/config.yaml"
+	objectStoreAuthPrefix      = "auths"
+	objectStoreRuntimeStateKey = "state/runtime-state.json"
 )
 
 // ObjectStoreConfig captures configuration for the object storage-backed token store.
@@ -324,6 +325,34 @@ func (s *ObjectTokenStore) PersistConfig(ctx context.Context) error {
 	return s.putObject(ctx, objectStoreConfigKey, data, "application/x-yaml")
 }
 
+// LoadRuntimeState downloads the runtime auth state from the object storage backend.
+func (s *ObjectTokenStore) LoadRuntimeState(ctx context.Context) ([]byte, error) {
+	key := s.prefixedKey(objectStoreRuntimeStateKey)
+	if _, err := s.client.StatObject(ctx, s.cfg.Bucket, key, minio.StatObjectOptions{}); err != nil {
+		if isObjectNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("object store: stat runtime state: %w", err)
+	}
+	object, err := s.client.GetObject(ctx, s.cfg.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("object store: fetch runtime state: %w", err)
+	}
+	defer object.Close()
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("object store: read runtime state: %w", err)
+	}
+	return data, nil
+}
+
+// SaveRuntimeState uploads the runtime auth state to the object storage backend.
+func (s *ObjectTokenStore) SaveRuntimeState(ctx context.Context, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.putObject(ctx, objectStoreRuntimeStateKey, data, "application/json")
+}
+
 func (s *ObjectTokenStore) ensureBucket(ctx context.Context) error {
 	exists, err := s.client.BucketExists(ctx, s.cfg.Bucket)
 	if err != nil {
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+)
+
+const (
+	defaultUsageRecordTable = "usage_records"
+	defaultUsageRollupTable = "usage_rollups"
+)
+
+// PostgresUsageStore keeps usage records and hourly rollups in the database of a PostgresStore.
+type PostgresUsageStore struct {
+	db          *sql.DB
+	recordTable string
+	rollupTable string
+}
+
+// OpenUsageStore implements usage.StoreOpener for the "postgres" usage-storage type, creating the
+// usage tables next to the config and auth tables.
+func (s *PostgresStore) OpenUsageStore(_ config.UsageStorage, _ string) (usage.Store, error) {
+	if s == nil || s.db == nil {
+		return nil, fmt.Errorf("postgres store: not initialized")
+	}
+	store := &PostgresUsageStore{
+		db:          s.db,
+		recordTable: s.fullTableName(defaultUsageRecordTable),
+		rollupTable: s.fullTableName(defaultUsageRollupTable),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := store.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *PostgresUsageStore) ensureSchema(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			requested_at TIMESTAMPTZ NOT NULL,
+			client_key TEXT NOT NULL DEFAULT '',
+			auth_id TEXT NOT NULL DEFAULT '',
+			provider TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL DEFAULT '',
+			failed BOOLEAN NOT NULL DEFAULT FALSE,
+			input_tokens BIGINT NOT NULL DEFAULT 0,
+			output_tokens BIGINT NOT NULL DEFAULT 0,
+			reasoning_tokens BIGINT NOT NULL DEFAULT 0,
+			cached_tokens BIGINT NOT NULL DEFAULT 0,
+			total_tokens BIGINT NOT NULL DEFAULT 0
+		)
+	`, s.recordTable)); err != nil {
+		return fmt.Errorf("postgres store: create usage record table: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS partial BOOLEAN NOT NULL DEFAULT FALSE`,
+		s.recordTable)); err != nil {
+		return fmt.Errorf("postgres store: add usage record partial column: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s ON %s (requested_at)`,
+		quoteIdentifier(defaultUsageRecordTable+"_requested_at_idx"), s.recordTable)); err != nil {
+		return fmt.Errorf("postgres store: create usage record index: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			hour TIMESTAMPTZ NOT NULL,
+			client_key TEXT NOT NULL DEFAULT '',
+			auth_id TEXT NOT NULL DEFAULT '',
+			provider TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			requests BIGINT NOT NULL DEFAULT 0,
+			failures BIGINT NOT NULL DEFAULT 0,
+			input_tokens BIGINT NOT NULL DEFAULT 0,
+			output_tokens BIGINT NOT NULL DEFAULT 0,
+			reasoning_tokens BIGINT NOT NULL DEFAULT 0,
+			cached_tokens BIGINT NOT NULL DEFAULT 0,
+			total_tokens BIGINT NOT NULL DEFAULT 0,
+			PRIMARY KEY (hour, client_key, auth_id, provider, model)
+		)
+	`, s.rollupTable)); err != nil {
+		return fmt.Errorf("postgres store: create usage rollup table: %w", err)
+	}
+	return nil
+}
+
+// Append implements usage.Store, inserting the records and updating their rollups in one transaction.
+func (s *PostgresUsageStore) Append(ctx context.Context, records []usage.StoredRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("postgres store: begin usage transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	insert, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (requested_at, client_key, auth_id, provider, model, source, failed, partial,
+			input_tokens, output_tokens, reasoning_tokens, cached_tokens, total_tokens)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`, s.recordTable))
+	if err != nil {
+		return fmt.Errorf("postgres store: prepare usage insert: %w", err)
+	}
+	defer func() { _ = insert.Close() }()
+	for _, r := range records {
+		if _, err = insert.ExecContext(ctx, r.Timestamp.UTC(), r.APIKey, r.AuthID, r.Provider, r.Model, r.Source, r.Failed, r.Partial,
+			r.Tokens.InputTokens, r.Tokens.OutputTokens, r.Tokens.ReasoningTokens, r.Tokens.CachedTokens, r.Tokens.TotalTokens); err != nil {
+			return fmt.Errorf("postgres store: insert usage record: %w", err)
+		}
+	}
+
+	upsert, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %[1]s AS t (hour, client_key, auth_id, provider, model, requests, failures,
+			input_tokens, output_tokens, reasoning_tokens, cached_tokens, total_tokens)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (hour, client_key, auth_id, provider, model) DO UPDATE SET
+			requests = t.requests + EXCLUDED.requests,
+			failures = t.failures + EXCLUDED.failures,
+			input_tokens = t.input_tokens + EXCLUDED.input_tokens,
+			output_tokens = t.output_tokens + EXCLUDED.output_tokens,
+			reasoning_tokens = t.reasoning_tokens + EXCLUDED.reasoning_tokens,
+			cached_tokens = t.cached_tokens + EXCLUDED.cached_tokens,
+			total_tokens = t.total_tokens + EXCLUDED.total_tokens
+	`, s.rollupTable))
+	if err != nil {
+		return fmt.Errorf("postgres store: prepare usage rollup: %w", err)
+	}
+	defer func() { _ = upsert.Close() }()
+	for _, r := range usage.RollupsOf(records) {
+		if _, err = upsert.ExecContext(ctx, r.Hour.UTC(), r.APIKey, r.AuthID, r.Provider, r.Model, r.Requests, r.Failures,
+			r.Tokens.InputTokens, r.Tokens.OutputTokens, r.Tokens.ReasoningTokens, r.Tokens.CachedTokens, r.Tokens.TotalTokens); err != nil {
+			return fmt.Errorf("postgres store: update usage rollup: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("postgres store: commit usage transaction: %w", err)
+	}
+	return nil
+}
+
+// Records implements usage.Store.
+func (s *PostgresUsageStore) Records(ctx context.Context, q usage.Query) ([]usage.StoredRecord, error) {
+	where, args := usageFilter("requested_at", q)
+	query := fmt.Sprintf(`
+		SELECT requested_at, client_key, auth_id, provider, model, source, failed, partial,
+			input_tokens, output_tokens, reasoning_tokens, cached_tokens, total_tokens
+		FROM %s%s ORDER BY requested_at DESC`, s.recordTable, where)
+	if q.Limit > 0 {
+		args = append(args, q.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("postgres store: query usage records: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []usage.StoredRecord
+	for rows.Next() {
+		var r usage.StoredRecord
+		if err = rows.Scan(&r.Timestamp, &r.APIKey, &r.AuthID, &r.Provider, &r.Model, &r.Source, &r.Failed, &r.Partial,
+			&r.Tokens.InputTokens, &r.Tokens.OutputTokens, &r.Tokens.ReasoningTokens, &r.Tokens.CachedTokens, &r.Tokens.TotalTokens); err != nil {
+			return nil, fmt.Errorf("postgres store: scan usage record: %w", err)
+		}
+		out = append(out, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres store: iterate usage records: %w", err)
+	}
+	return out, nil
+}
+
+// Rollups implements usage.Store.
+func (s *PostgresUsageStore) Rollups(ctx context.Context, q usage.Query) ([]usage.Rollup, error) {
+	where, args := usageFilter("hour", q)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT hour, client_key, auth_id, provider, model, requests, failures,
+			input_tokens, output_tokens, reasoning_tokens, cached_tokens, total_tokens
+		FROM %s%s ORDER BY hour`, s.rollupTable, where), args...)
+	if err != nil {
+		return nil, fmt.Errorf("postgres store: query usage rollups: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []usage.Rollup
+	for rows.Next() {
+		var r usage.Rollup
+		if err = rows.Scan(&r.Hour, &r.APIKey, &r.AuthID, &r.Provider, &r.Model, &r.Requests, &r.Failures,
+			&r.Tokens.InputTokens, &r.Tokens.OutputTokens, &r.Tokens.ReasoningTokens, &r.Tokens.CachedTokens, &r.Tokens.TotalTokens); err != nil {
+			return nil, fmt.Errorf("postgres store: scan usage rollup: %w", err)
+		}
+		out = append(out, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("postgres store: iterate usage rollups: %w", err)
+	}
+	return out, nil
+}
+
+// Prune implements usage.Store.
+func (s *PostgresUsageStore) Prune(ctx context.Context, recordsBefore, rollupsBefore time.Time) error {
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE requested_at < $1`, s.recordTable), recordsBefore.UTC()); err != nil {
+		return fmt.Errorf("postgres store: prune usage records: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE hour < $1`, s.rollupTable), rollupsBefore.UTC()); err != nil {
+		return fmt.Errorf("postgres store: prune usage rollups: %w", err)
+	}
+	return nil
+}
+
+// Close implements usage.Store. The connection belongs to the PostgresStore and stays open.
+func (s *PostgresUsageStore) Close() error { return nil }
+
+// usageFilter builds the WHERE clause of a usage query on the given time column.
+func usageFilter(timeColumn string, q usage.Query) (string, []any) {
+	var conditions []string
+	var args []any
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if !q.From.IsZero() {
+		add(timeColumn+" >= $%d", q.From.UTC())
+	}
+	if !q.To.IsZero() {
+		add(timeColumn+" < $%d", q.To.UTC())
+	}
+	if q.APIKey != "" {
+		add("client_key = $%d", q.APIKey)
+	}
+	if q.AuthID != "" {
+		add("auth_id = $%d", q.AuthID)
+	}
+	if q.Provider != "" {
+		add("LOWER(provider) = LOWER($%d)", q.Provider)
+	}
+	if q.Model != "" {
+		add("model = $%d", q.Model)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
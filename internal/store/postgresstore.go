@@ -20,9 +20,10 @@ import (
 )
 
 const (
-	defaultConfigTable = "config_store"
-	defaultAuthTable   = "auth_store"
-	defaultConfigKey   = "config"
+	defaultConfigTable     = "config_store"
+	defaultAuthTable       = "auth_store"
+	defaultConfigKey       = "config"
+	defaultRuntimeStateKey = "runtime-state"
 )
 
 // PostgresStoreConfig captures configuration required to initialize a Postgres-backed store.
@@ -391,6 +392,34 @@ func (s *PostgresStore) PersistConfig(ctx context.Context) error {
 	return s.persistConfig(ctx, data)
 }
 
+// LoadRuntimeState reads the runtime auth state row from the config table.
+func (s *PostgresStore) LoadRuntimeState(ctx context.Context) ([]byte, error) {
+	query := fmt.Sprintf("SELECT content FROM %s WHERE id = $1", s.fullTableName(s.cfg.ConfigTable))
+	var content string
+	err := s.db.QueryRowContext(ctx, query, defaultRuntimeStateKey).Scan(&content)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("postgres store: load runtime state: %w", err)
+	}
+	return []byte(content), nil
+}
+
+// SaveRuntimeState upserts the runtime auth state row in the config table.
+func (s *PostgresStore) SaveRuntimeState(ctx context.Context, data []byte) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (id, content, created_at, updated_at)
+		VALUES ($1, $2, NOW(), NOW())
+		ON CONFLICT (id)
+		DO UPDATE SET content = EXCLUDED.content, updated_at = NOW()
+	`, s.fullTableName(s.cfg.ConfigTable))
+	if _, err := s.db.ExecContext(ctx, query, defaultRuntimeStateKey, string(data)); err != nil {
+		return fmt.Errorf("postgres store: upsert runtime state: %w", err)
+	}
+	return nil
+}
+
 // syncConfigFromDatabase writes the database-stored config to disk or seeds the database from template.
 func (s *PostgresStore) syncConfigFromDatabase(ctx context.Context, exampleConfigPath string) error {
 	query := fmt.Sprintf("SELECT content FROM %s WHERE id = $1", s.fullTableName(s.cfg.ConfigTable))
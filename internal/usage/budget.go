@@ -0,0 +1,265 @@
+package usage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+func init() {
+	coreusage.RegisterPlugin(&budgetPlugin{tracker: defaultBudgetTracker})
+}
+
+// BudgetTracker counts requests and tokens per client API key against daily and monthly budgets.
+// Requests are counted when admitted; tokens arrive from the usage record stream once upstream
+// responses complete. Counters live in memory; with usage storage enabled they are seeded from the
+// stored rollups at startup so restarts do not reset budgets.
+type BudgetTracker struct {
+	mu      sync.Mutex
+	budgets map[string]sdkconfig.ClientKeyBudget
+	usage   map[string]*budgetUsage
+	// seeded holds counters restored from storage by client identity, taken over by a key's first use.
+	seeded map[string]*budgetUsage
+}
+
+type budgetUsage struct {
+	day             string
+	month           string
+	dailyTokens     int64
+	monthlyTokens   int64
+	dailyRequests   int64
+	monthlyRequests int64
+}
+
+// BudgetStatus reports the budget of one client key. Remaining values are nil for unlimited budgets.
+type BudgetStatus struct {
+	APIKey                   string                    `json:"api-key"`
+	Budget                   sdkconfig.ClientKeyBudget `json:"budget"`
+	DailyTokens              int64                     `json:"daily-tokens-used"`
+	MonthlyTokens            int64                     `json:"monthly-tokens-used"`
+	DailyRequests            int64                     `json:"daily-requests-used"`
+	MonthlyRequests          int64                     `json:"monthly-requests-used"`
+	DailyTokensRemaining     *int64                    `json:"daily-tokens-remaining,omitempty"`
+	MonthlyTokensRemaining   *int64                    `json:"monthly-tokens-remaining,omitempty"`
+	DailyRequestsRemaining   *int64                    `json:"daily-requests-remaining,omitempty"`
+	MonthlyRequestsRemaining *int64                    `json:"monthly-requests-remaining,omitempty"`
+	// Exhausted names the exhausted budget that resets last, empty while requests are admitted.
+	Exhausted string `json:"exhausted,omitempty"`
+	// ResetAt is when the exhausted budget starts over.
+	ResetAt *time.Time `json:"reset-at,omitempty"`
+}
+
+var defaultBudgetTracker = NewBudgetTracker()
+
+// NewBudgetTracker constructs an empty budget tracker.
+func NewBudgetTracker() *BudgetTracker {
+	return &BudgetTracker{
+		budgets: make(map[string]sdkconfig.ClientKeyBudget),
+		usage:   make(map[string]*budgetUsage),
+	}
+}
+
+// GetBudgetTracker returns the shared budget tracker fed by the usage record stream.
+func GetBudgetTracker() *BudgetTracker { return defaultBudgetTracker }
+
+// SetBudgets replaces the budgets from the client-keys configuration. Usage counters are kept.
+func (t *BudgetTracker) SetBudgets(keys []sdkconfig.ClientKey) {
+	if t == nil {
+		return
+	}
+	budgets := make(map[string]sdkconfig.ClientKeyBudget, len(keys))
+	for i := range keys {
+		principal := keys[i].Principal()
+		if principal == "" || keys[i].Budget.Unlimited() {
+			continue
+		}
+		budgets[principal] = keys[i].Budget
+	}
+	t.mu.Lock()
+	t.budgets = budgets
+	t.mu.Unlock()
+}
+
+// Admit counts a request against the budget of apiKey. limited reports whether the key has a
+// budget at all; admitted is false, and the request is not counted, when a budget is exhausted.
+func (t *BudgetTracker) Admit(apiKey string, now time.Time) (status BudgetStatus, limited bool, admitted bool) {
+	if t == nil || apiKey == "" {
+		return BudgetStatus{}, false, true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	budget, ok := t.budgets[apiKey]
+	if !ok {
+		return BudgetStatus{}, false, true
+	}
+	u := t.usageLocked(apiKey, now)
+	status = budgetStatus(apiKey, budget, u, now)
+	if status.Exhausted != "" {
+		return status, true, false
+	}
+	u.dailyRequests++
+	u.monthlyRequests++
+	return budgetStatus(apiKey, budget, u, now), true, true
+}
+
+// Statuses returns the budget status of every key with a budget, sorted by key.
+func (t *BudgetTracker) Statuses(now time.Time) []BudgetStatus {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	out := make([]BudgetStatus, 0, len(t.budgets))
+	for key, budget := range t.budgets {
+		out = append(out, budgetStatus(key, budget, t.usageLocked(key, now), now))
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].APIKey < out[j].APIKey })
+	return out
+}
+
+// Reset clears the usage counters of apiKey.
+func (t *BudgetTracker) Reset(apiKey string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	delete(t.usage, apiKey)
+	delete(t.seeded, ClientIdentity(apiKey))
+	t.mu.Unlock()
+}
+
+// Seed restores the counters of the current day and month from stored hourly rollups. Requests
+// and tokens recorded since the process started are not in storage yet, so keys that already have
+// counters keep them.
+func (t *BudgetTracker) Seed(rollups []Rollup, now time.Time) {
+	if t == nil {
+		return
+	}
+	day, month := now.Format("2006-01-02"), now.Format("2006-01")
+	seeded := make(map[string]*budgetUsage)
+	for _, rollup := range rollups {
+		hour := rollup.Hour.In(now.Location())
+		identity := ClientIdentity(rollup.APIKey)
+		if identity == "" || hour.Format("2006-01") != month {
+			continue
+		}
+		u, ok := seeded[identity]
+		if !ok {
+			u = &budgetUsage{day: day, month: month}
+			seeded[identity] = u
+		}
+		u.monthlyTokens += rollup.Tokens.TotalTokens
+		u.monthlyRequests += rollup.Requests
+		if hour.Format("2006-01-02") == day {
+			u.dailyTokens += rollup.Tokens.TotalTokens
+			u.dailyRequests += rollup.Requests
+		}
+	}
+	t.mu.Lock()
+	t.seeded = seeded
+	t.mu.Unlock()
+}
+
+func (t *BudgetTracker) record(apiKey string, tokens int64, now time.Time) {
+	if t == nil || apiKey == "" || tokens <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.budgets[apiKey]; !ok {
+		return
+	}
+	u := t.usageLocked(apiKey, now)
+	u.dailyTokens += tokens
+	u.monthlyTokens += tokens
+}
+
+// usageLocked returns the counters of apiKey, rolling them over at day and month boundaries.
+func (t *BudgetTracker) usageLocked(apiKey string, now time.Time) *budgetUsage {
+	u, ok := t.usage[apiKey]
+	if !ok {
+		u = &budgetUsage{}
+		identity := ClientIdentity(apiKey)
+		if seed, okSeed := t.seeded[identity]; okSeed {
+			*u = *seed
+			delete(t.seeded, identity)
+		}
+		t.usage[apiKey] = u
+	}
+	if day := now.Format("2006-01-02"); u.day != day {
+		u.day = day
+		u.dailyTokens = 0
+		u.dailyRequests = 0
+	}
+	if month := now.Format("2006-01"); u.month != month {
+		u.month = month
+		u.monthlyTokens = 0
+		u.monthlyRequests = 0
+	}
+	return u
+}
+
+func budgetStatus(apiKey string, budget sdkconfig.ClientKeyBudget, u *budgetUsage, now time.Time) BudgetStatus {
+	status := BudgetStatus{
+		APIKey:          apiKey,
+		Budget:          budget,
+		DailyTokens:     u.dailyTokens,
+		MonthlyTokens:   u.monthlyTokens,
+		DailyRequests:   u.dailyRequests,
+		MonthlyRequests: u.monthlyRequests,
+	}
+	nextDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	nextMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location())
+	checks := []struct {
+		name      string
+		limit     int64
+		used      int64
+		remaining **int64
+		resetAt   time.Time
+	}{
+		{"daily-tokens", budget.DailyTokens, u.dailyTokens, &status.DailyTokensRemaining, nextDay},
+		{"monthly-tokens", budget.MonthlyTokens, u.monthlyTokens, &status.MonthlyTokensRemaining, nextMonth},
+		{"daily-requests", budget.DailyRequests, u.dailyRequests, &status.DailyRequestsRemaining, nextDay},
+		{"monthly-requests", budget.MonthlyRequests, u.monthlyRequests, &status.MonthlyRequestsRemaining, nextMonth},
+	}
+	for _, check := range checks {
+		if check.limit <= 0 {
+			continue
+		}
+		remaining := check.limit - check.used
+		if remaining < 0 {
+			remaining = 0
+		}
+		*check.remaining = &remaining
+		if remaining == 0 && (status.ResetAt == nil || check.resetAt.After(*status.ResetAt)) {
+			resetAt := check.resetAt
+			status.Exhausted = check.name
+			status.ResetAt = &resetAt
+		}
+	}
+	return status
+}
+
+// budgetPlugin charges the tokens of each usage record to the client key that made the request.
+type budgetPlugin struct {
+	tracker *BudgetTracker
+}
+
+// HandleUsage implements coreusage.Plugin. Tokens are charged to the client key the request was
+// admitted under, never to a principal of another access provider that happens to match a key.
+func (p *budgetPlugin) HandleUsage(ctx context.Context, record coreusage.Record) {
+	if p == nil || p.tracker == nil || ctx == nil {
+		return
+	}
+	ginCtx, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || ginCtx == nil {
+		return
+	}
+	// Charge the current window: a request started before midnight still spends today's budget.
+	p.tracker.record(ginCtx.GetString("clientKey"), normaliseDetail(record.Detail).TotalTokens, time.Now())
+}
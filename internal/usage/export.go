@@ -0,0 +1,468 @@
+package usage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultExportBatchSize     = 100
+	defaultExportFlushInterval = 10 * time.Second
+	defaultExportTimeout       = 10 * time.Second
+	defaultExportMaxRetries    = 3
+	defaultExportMaxBufferMB   = 100
+	exportRetryBaseDelay       = time.Second
+	exportRetryMaxDelay        = 30 * time.Second
+	// exportMaxPending bounds the records held in memory between flushes.
+	exportMaxPending = 10000
+	exportBufferExt  = ".ndjson"
+
+	// ExportSignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the timestamp header value,
+	// a ".", and the request body.
+	ExportSignatureHeader = "X-Usage-Signature"
+	// ExportTimestampHeader carries the Unix time in seconds the request was signed at.
+	ExportTimestampHeader = "X-Usage-Timestamp"
+)
+
+func init() {
+	coreusage.RegisterPlugin(defaultExporter)
+}
+
+// ExportedRecord is a usage record as sent to the collector. Like stored records it carries the
+// client identity, never the client key.
+type ExportedRecord struct {
+	StoredRecord
+	// Cost is the list price of the tokens in US dollars; zero when the model has no price.
+	Cost float64 `json:"cost"`
+}
+
+// errExportRejected marks batches the collector refused; they are dropped instead of retried.
+var errExportRejected = errors.New("usage export: collector rejected batch")
+
+// exporter batches usage records and POSTs them to the configured collector, buffering batches on
+// disk while the collector is unreachable.
+type exporter struct {
+	mu       sync.Mutex
+	settings *exportSettings
+	pending  []ExportedRecord
+	wake     chan struct{}
+	loopOnce sync.Once
+	flushMu  sync.Mutex
+}
+
+// exportSettings is the resolved usage-export configuration.
+type exportSettings struct {
+	url        string
+	format     string
+	headers    map[string]string
+	batchSize  int
+	interval   time.Duration
+	maxRetries int
+	secret     []byte
+	dir        string
+	maxBuffer  int64
+	client     *http.Client
+}
+
+var (
+	defaultExporter = &exporter{wake: make(chan struct{}, 1)}
+	exportBufferSeq atomic.Uint64
+)
+
+// ConfigureExport applies the usage-export settings. Batches buffered on disk by an earlier run are
+// sent once the collector accepts requests again.
+func ConfigureExport(cfg config.UsageExport, baseDir string) error {
+	settings, err := newExportSettings(cfg, baseDir)
+	if err != nil {
+		return err
+	}
+	e := defaultExporter
+	e.mu.Lock()
+	previous := e.settings
+	e.settings = settings
+	e.mu.Unlock()
+	if settings == nil {
+		if previous != nil {
+			log.Info("usage export disabled")
+		}
+		return nil
+	}
+	e.loopOnce.Do(func() { go e.run() })
+	e.signal()
+	if previous == nil {
+		log.Infof("usage export enabled (%s, %s)", settings.url, settings.format)
+	}
+	return nil
+}
+
+// CloseExport buffers the records not yet sent on disk; they are delivered after the next start.
+func CloseExport() {
+	e := defaultExporter
+	e.flushMu.Lock()
+	defer e.flushMu.Unlock()
+	e.mu.Lock()
+	settings := e.settings
+	pending := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+	if settings != nil {
+		settings.buffer(pending)
+	}
+}
+
+func newExportSettings(cfg config.UsageExport, baseDir string) (*exportSettings, error) {
+	rawURL := strings.TrimSpace(cfg.URL)
+	if rawURL == "" {
+		return nil, nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("usage export: invalid url %q", rawURL)
+	}
+	format := strings.ToLower(strings.TrimSpace(cfg.Format))
+	switch format {
+	case "":
+		format = config.UsageExportJSON
+	case config.UsageExportJSON, config.UsageExportNDJSON:
+	default:
+		return nil, fmt.Errorf("usage export: unsupported format %q", cfg.Format)
+	}
+	dir := strings.TrimSpace(cfg.BufferDir)
+	switch {
+	case dir == "" && util.WritablePath() != "":
+		dir = filepath.Join(util.WritablePath(), "usage-export")
+	case dir == "":
+		dir = filepath.Join(baseDir, "usage-export")
+	case !filepath.IsAbs(dir):
+		dir = filepath.Join(baseDir, dir)
+	}
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("usage export: create buffer directory: %w", err)
+	}
+	settings := &exportSettings{
+		url:        rawURL,
+		format:     format,
+		headers:    cfg.Headers,
+		batchSize:  cfg.BatchSize,
+		interval:   time.Duration(cfg.FlushIntervalSeconds) * time.Second,
+		maxRetries: cfg.MaxRetries,
+		dir:        dir,
+		maxBuffer:  int64(cfg.MaxBufferMB) << 20,
+		client:     &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second},
+	}
+	if settings.batchSize <= 0 {
+		settings.batchSize = defaultExportBatchSize
+	}
+	if settings.interval <= 0 {
+		settings.interval = defaultExportFlushInterval
+	}
+	if settings.client.Timeout <= 0 {
+		settings.client.Timeout = defaultExportTimeout
+	}
+	switch {
+	case settings.maxRetries == 0:
+		settings.maxRetries = defaultExportMaxRetries
+	case settings.maxRetries < 0:
+		settings.maxRetries = 0
+	}
+	if settings.maxBuffer <= 0 {
+		settings.maxBuffer = defaultExportMaxBufferMB << 20
+	}
+	if cfg.HMACSecret != "" {
+		settings.secret = []byte(cfg.HMACSecret)
+	}
+	return settings, nil
+}
+
+// HandleUsage implements coreusage.Plugin by queueing the record for the next batch.
+func (e *exporter) HandleUsage(ctx context.Context, record coreusage.Record) {
+	e.mu.Lock()
+	enabled := e.settings != nil
+	e.mu.Unlock()
+	if !enabled {
+		return
+	}
+	stored := storedRecordOf(ctx, record)
+	exported := ExportedRecord{StoredRecord: stored, Cost: Prices().Cost(stored.Provider, stored.Model, stored.Tokens)}
+	e.mu.Lock()
+	if e.settings == nil {
+		e.mu.Unlock()
+		return
+	}
+	e.pending = append(e.pending, exported)
+	if dropped := len(e.pending) - exportMaxPending; dropped > 0 {
+		e.pending = append(e.pending[:0], e.pending[dropped:]...)
+		log.Warnf("usage export: dropped %d unsent records", dropped)
+	}
+	full := len(e.pending) >= e.settings.batchSize
+	e.mu.Unlock()
+	if full {
+		e.signal()
+	}
+}
+
+func (e *exporter) signal() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (e *exporter) run() {
+	for {
+		interval := defaultExportFlushInterval
+		e.mu.Lock()
+		if e.settings != nil {
+			interval = e.settings.interval
+		}
+		e.mu.Unlock()
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-e.wake:
+			timer.Stop()
+		}
+		e.flush()
+	}
+}
+
+// flush sends the buffered batches, oldest first, and then the pending records. Once the collector
+// fails, everything not yet sent is buffered on disk so records stay in order.
+func (e *exporter) flush() {
+	e.flushMu.Lock()
+	defer e.flushMu.Unlock()
+	e.mu.Lock()
+	settings := e.settings
+	pending := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+	if settings == nil {
+		return
+	}
+	if !settings.sendBuffered() {
+		settings.buffer(pending)
+		return
+	}
+	for len(pending) > 0 {
+		n := min(settings.batchSize, len(pending))
+		err := settings.deliver(pending[:n])
+		switch {
+		case errors.Is(err, errExportRejected):
+			log.Errorf("%v; dropped %d records", err, n)
+		case err != nil:
+			log.Warnf("%v; buffering %d records", err, len(pending))
+			settings.buffer(pending)
+			return
+		}
+		pending = pending[n:]
+	}
+}
+
+// sendBuffered delivers the batches buffered on disk and reports whether all of them were handled.
+func (s *exportSettings) sendBuffered() bool {
+	for _, name := range s.bufferFiles() {
+		path := filepath.Join(s.dir, name)
+		records, err := readExportBuffer(path)
+		if err != nil {
+			log.Errorf("usage export: %v; dropping buffered batch", err)
+			_ = os.Remove(path)
+			continue
+		}
+		for len(records) > 0 {
+			n := min(s.batchSize, len(records))
+			errDeliver := s.deliver(records[:n])
+			if errDeliver != nil && !errors.Is(errDeliver, errExportRejected) {
+				log.Warnf("%v; keeping buffered batches", errDeliver)
+				if errWrite := writeExportBuffer(path, records); errWrite != nil {
+					log.Errorf("usage export: %v", errWrite)
+				}
+				return false
+			}
+			if errDeliver != nil {
+				log.Errorf("%v; dropped %d buffered records", errDeliver, n)
+			}
+			records = records[n:]
+		}
+		if errRemove := os.Remove(path); errRemove != nil && !errors.Is(errRemove, os.ErrNotExist) {
+			log.Errorf("usage export: remove buffered batch: %v", errRemove)
+		}
+	}
+	return true
+}
+
+// deliver POSTs one batch, retrying transient failures with exponential backoff.
+func (s *exportSettings) deliver(records []ExportedRecord) error {
+	body, contentType, err := s.encode(records)
+	if err != nil {
+		return err
+	}
+	delay := exportRetryBaseDelay
+	for attempt := 0; ; attempt++ {
+		err = s.post(body, contentType)
+		if err == nil || errors.Is(err, errExportRejected) || attempt >= s.maxRetries {
+			return err
+		}
+		time.Sleep(delay)
+		delay = min(delay*2, exportRetryMaxDelay)
+	}
+}
+
+func (s *exportSettings) encode(records []ExportedRecord) ([]byte, string, error) {
+	if s.format == config.UsageExportNDJSON {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return nil, "", fmt.Errorf("usage export: encode record: %w", err)
+			}
+		}
+		return buf.Bytes(), "application/x-ndjson", nil
+	}
+	body, err := json.Marshal(records)
+	if err != nil {
+		return nil, "", fmt.Errorf("usage export: encode batch: %w", err)
+	}
+	return body, "application/json", nil
+}
+
+func (s *exportSettings) post(body []byte, contentType string) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("usage export: build request: %w", err)
+	}
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if len(s.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		req.Header.Set(ExportTimestampHeader, timestamp)
+		req.Header.Set(ExportSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("usage export: post: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("usage export: collector returned %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w with status %d", errExportRejected, resp.StatusCode)
+	}
+}
+
+// buffer writes records to a new file in the buffer directory and drops the oldest files beyond the
+// size cap.
+func (s *exportSettings) buffer(records []ExportedRecord) {
+	if len(records) == 0 {
+		return
+	}
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), exportBufferSeq.Add(1)%1000000, exportBufferExt)
+	if err := writeExportBuffer(filepath.Join(s.dir, name), records); err != nil {
+		log.Errorf("usage export: %v; dropped %d records", err, len(records))
+		return
+	}
+	files := s.bufferFiles()
+	sizes := make([]int64, len(files))
+	var total int64
+	for i, file := range files {
+		if info, err := os.Stat(filepath.Join(s.dir, file)); err == nil {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+	for i := 0; total > s.maxBuffer && i < len(files)-1; i++ {
+		if err := os.Remove(filepath.Join(s.dir, files[i])); err != nil {
+			continue
+		}
+		total -= sizes[i]
+		log.Warnf("usage export: buffer exceeds %d MB, dropped batch %s", s.maxBuffer>>20, files[i])
+	}
+}
+
+// bufferFiles lists the buffered batches, oldest first.
+func (s *exportSettings) bufferFiles() []string {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		log.Errorf("usage export: list buffer: %v", err)
+		return nil
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), exportBufferExt) {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func writeExportBuffer(path string, records []ExportedRecord) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("encode buffered record: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("write buffer: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replace buffer: %w", err)
+	}
+	return nil
+}
+
+func readExportBuffer(path string) ([]ExportedRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open buffer: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+	var records []ExportedRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record ExportedRecord
+		if json.Unmarshal(scanner.Bytes(), &record) != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read buffer %s: %w", filepath.Base(path), err)
+	}
+	return records, nil
+}
@@ -0,0 +1,346 @@
+package usage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+)
+
+const (
+	fileStoreDayLayout  = "2006-01-02"
+	fileStoreRecordsDir = "records"
+	fileStoreRollupsDir = "rollups"
+)
+
+// FileStore keeps usage in one append-only JSONL file of records and one JSON file of hourly
+// rollups per UTC day.
+type FileStore struct {
+	mu      sync.Mutex
+	dir     string
+	rollups map[string]map[rollupKey]*Rollup
+}
+
+func openFileStore(cfg config.UsageStorage, baseDir string) (Store, error) {
+	dir := strings.TrimSpace(cfg.Dir)
+	switch {
+	case dir == "" && util.WritablePath() != "":
+		dir = filepath.Join(util.WritablePath(), "usage")
+	case dir == "":
+		dir = filepath.Join(baseDir, "usage")
+	case !filepath.IsAbs(dir):
+		dir = filepath.Join(baseDir, dir)
+	}
+	return NewFileStore(dir)
+}
+
+// NewFileStore creates the directories of a file store rooted at dir.
+func NewFileStore(dir string) (*FileStore, error) {
+	for _, sub := range []string{fileStoreRecordsDir, fileStoreRollupsDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, fmt.Errorf("usage storage: create %s directory: %w", sub, err)
+		}
+	}
+	return &FileStore{dir: dir, rollups: make(map[string]map[rollupKey]*Rollup)}, nil
+}
+
+// Append implements Store. Days are written one at a time, rollups first: once the rollups of a
+// day are replaced its records count as stored, so a failed batch reports only the days still
+// unwritten in an AppendError and retrying it never counts a record twice.
+func (s *FileStore) Append(_ context.Context, records []StoredRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	byDay := make(map[string][]StoredRecord)
+	days := make([]string, 0, 1)
+	for _, record := range records {
+		day := record.Timestamp.UTC().Format(fileStoreDayLayout)
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], record)
+	}
+	sort.Strings(days)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, day := range days {
+		if err := s.appendDay(day, byDay[day]); err != nil {
+			var unwritten []StoredRecord
+			for _, rest := range days[i:] {
+				unwritten = append(unwritten, byDay[rest]...)
+			}
+			return &AppendError{Unwritten: unwritten, Err: err}
+		}
+		if err := s.appendRecords(day, byDay[day]); err != nil {
+			// The rollups already count these records; retrying would count them twice.
+			var unwritten []StoredRecord
+			for _, rest := range days[i+1:] {
+				unwritten = append(unwritten, byDay[rest]...)
+			}
+			return &AppendError{Unwritten: unwritten, Err: err}
+		}
+	}
+	return nil
+}
+
+// appendDay adds records to the rollups of day. The cache is only updated once the file is written.
+func (s *FileStore) appendDay(day string, records []StoredRecord) error {
+	cached, err := s.dayRollups(day)
+	if err != nil {
+		return err
+	}
+	rollups := make(map[rollupKey]*Rollup, len(cached))
+	for key, rollup := range cached {
+		copied := *rollup
+		rollups[key] = &copied
+	}
+	for _, delta := range RollupsOf(records) {
+		key := delta.key()
+		if existing, ok := rollups[key]; ok {
+			existing.Requests += delta.Requests
+			existing.Failures += delta.Failures
+			existing.Tokens.add(delta.Tokens)
+			continue
+		}
+		rollup := delta
+		rollups[key] = &rollup
+	}
+	if err = s.writeRollups(day, rollups); err != nil {
+		return err
+	}
+	s.rollups[day] = rollups
+	return nil
+}
+
+func (s *FileStore) appendRecords(day string, records []StoredRecord) error {
+	path := filepath.Join(s.dir, fileStoreRecordsDir, day+".jsonl")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("usage storage: open records: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err = encoder.Encode(record); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("usage storage: encode record: %w", err)
+		}
+	}
+	if err = writer.Flush(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("usage storage: write records: %w", err)
+	}
+	return file.Close()
+}
+
+// dayRollups returns the cached rollups of day, loading them from disk on first use.
+func (s *FileStore) dayRollups(day string) (map[rollupKey]*Rollup, error) {
+	if rollups, ok := s.rollups[day]; ok {
+		return rollups, nil
+	}
+	list, err := s.readRollups(day)
+	if err != nil {
+		return nil, err
+	}
+	rollups := make(map[rollupKey]*Rollup, len(list))
+	for i := range list {
+		rollups[list[i].key()] = &list[i]
+	}
+	// Only recent days are written to; keep the cache from growing with old days.
+	for cached := range s.rollups {
+		if cached < day {
+			delete(s.rollups, cached)
+		}
+	}
+	s.rollups[day] = rollups
+	return rollups, nil
+}
+
+func (s *FileStore) readRollups(day string) ([]Rollup, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, fileStoreRollupsDir, day+".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("usage storage: read rollups: %w", err)
+	}
+	var list []Rollup
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("usage storage: decode rollups %s: %w", day, err)
+	}
+	return list, nil
+}
+
+func (s *FileStore) writeRollups(day string, rollups map[rollupKey]*Rollup) error {
+	list := make([]Rollup, 0, len(rollups))
+	for _, rollup := range rollups {
+		list = append(list, *rollup)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Hour.Before(list[j].Hour) })
+	data, err := json.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("usage storage: encode rollups: %w", err)
+	}
+	path := filepath.Join(s.dir, fileStoreRollupsDir, day+".json")
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("usage storage: write rollups: %w", err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("usage storage: replace rollups: %w", err)
+	}
+	return nil
+}
+
+// Records implements Store.
+func (s *FileStore) Records(_ context.Context, q Query) ([]StoredRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	days, err := s.days(fileStoreRecordsDir, ".jsonl", q)
+	if err != nil {
+		return nil, err
+	}
+	var out []StoredRecord
+	for _, day := range days {
+		file, errOpen := os.Open(filepath.Join(s.dir, fileStoreRecordsDir, day+".jsonl"))
+		if errOpen != nil {
+			return nil, fmt.Errorf("usage storage: open records: %w", errOpen)
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var record StoredRecord
+			if json.Unmarshal(scanner.Bytes(), &record) != nil {
+				// A crash can leave a truncated last line behind.
+				continue
+			}
+			if inRange(record.Timestamp, q) && q.matches(record.APIKey, record.AuthID, record.Provider, record.Model) {
+				out = append(out, record)
+			}
+		}
+		errScan := scanner.Err()
+		_ = file.Close()
+		if errScan != nil {
+			return nil, fmt.Errorf("usage storage: read records: %w", errScan)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Timestamp.After(out[j].Timestamp) })
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+// Rollups implements Store.
+func (s *FileStore) Rollups(_ context.Context, q Query) ([]Rollup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	days, err := s.days(fileStoreRollupsDir, ".json", q)
+	if err != nil {
+		return nil, err
+	}
+	var out []Rollup
+	for _, day := range days {
+		var list []Rollup
+		if cached, ok := s.rollups[day]; ok {
+			for _, rollup := range cached {
+				list = append(list, *rollup)
+			}
+		} else if list, err = s.readRollups(day); err != nil {
+			return nil, err
+		}
+		for _, rollup := range list {
+			if inRange(rollup.Hour, q) && q.matches(rollup.APIKey, rollup.AuthID, rollup.Provider, rollup.Model) {
+				out = append(out, rollup)
+			}
+		}
+	}
+	return out, nil
+}
+
+// Prune implements Store. Whole days are deleted once they end before the cutoff.
+func (s *FileStore) Prune(_ context.Context, recordsBefore, rollupsBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.pruneDir(fileStoreRecordsDir, ".jsonl", recordsBefore); err != nil {
+		return err
+	}
+	if err := s.pruneDir(fileStoreRollupsDir, ".json", rollupsBefore); err != nil {
+		return err
+	}
+	for day := range s.rollups {
+		if dayEnd(day).After(rollupsBefore) {
+			continue
+		}
+		delete(s.rollups, day)
+	}
+	return nil
+}
+
+func (s *FileStore) pruneDir(sub, ext string, before time.Time) error {
+	days, err := s.days(sub, ext, Query{})
+	if err != nil {
+		return err
+	}
+	for _, day := range days {
+		if dayEnd(day).After(before) {
+			continue
+		}
+		if errRemove := os.Remove(filepath.Join(s.dir, sub, day+ext)); errRemove != nil && !errors.Is(errRemove, fs.ErrNotExist) {
+			return fmt.Errorf("usage storage: prune %s: %w", day, errRemove)
+		}
+	}
+	return nil
+}
+
+// Close implements Store. Every Append is written through, so there is nothing to flush.
+func (s *FileStore) Close() error { return nil }
+
+// days lists the days with a file in sub that overlap the query range, oldest first.
+func (s *FileStore) days(sub, ext string, q Query) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, sub))
+	if err != nil {
+		return nil, fmt.Errorf("usage storage: list %s: %w", sub, err)
+	}
+	var days []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		day := strings.TrimSuffix(name, ext)
+		start, errParse := time.Parse(fileStoreDayLayout, day)
+		if errParse != nil {
+			continue
+		}
+		if !q.To.IsZero() && !start.Before(q.To) {
+			continue
+		}
+		if !q.From.IsZero() && !dayEnd(day).After(q.From) {
+			continue
+		}
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+func dayEnd(day string) time.Time {
+	start, _ := time.Parse(fileStoreDayLayout, day)
+	return start.AddDate(0, 0, 1)
+}
+
+func inRange(t time.Time, q Query) bool {
+	return (q.From.IsZero() || !t.Before(q.From)) && (q.To.IsZero() || t.Before(q.To))
+}
@@ -0,0 +1,62 @@
+package usage
+
+import (
+	"strings"
+	"sync"
+
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+)
+
+// keyDigestLength is the number of hex digits of the SHA-256 digest naming keys without an ID.
+const keyDigestLength = 12
+
+// clientIdentities maps configured client secrets to the identifiers their usage is recorded under,
+// so statistics, storage and exports never carry the secrets themselves.
+var clientIdentities = struct {
+	sync.RWMutex
+	byKey map[string]string
+}{byKey: make(map[string]string)}
+
+// SetClientKeys registers the api-keys and client-keys of the configuration. Usage of a client key
+// is recorded under its ID, other keys under "key-" and a truncated SHA-256 digest of the key.
+func SetClientKeys(apiKeys []string, clientKeys []sdkconfig.ClientKey) {
+	byKey := make(map[string]string, len(apiKeys)+len(clientKeys))
+	for _, key := range apiKeys {
+		if key = strings.TrimSpace(key); key != "" {
+			byKey[key] = keyDigest(key)
+		}
+	}
+	for i := range clientKeys {
+		key := strings.TrimSpace(clientKeys[i].Key)
+		if key == "" {
+			continue
+		}
+		if id := strings.TrimSpace(clientKeys[i].ID); id != "" {
+			byKey[key] = id
+		} else {
+			byKey[key] = keyDigest(key)
+		}
+	}
+	clientIdentities.Lock()
+	clientIdentities.byKey = byKey
+	clientIdentities.Unlock()
+}
+
+// ClientIdentity returns the identifier usage of principal is recorded under. Principals that are
+// not configured keys, such as hashed client key IDs or token subjects, are returned unchanged.
+func ClientIdentity(principal string) string {
+	if principal == "" {
+		return ""
+	}
+	clientIdentities.RLock()
+	identity, ok := clientIdentities.byKey[principal]
+	clientIdentities.RUnlock()
+	if ok {
+		return identity
+	}
+	return principal
+}
+
+func keyDigest(key string) string {
+	return "key-" + strings.TrimPrefix(sdkconfig.HashClientKey(key), "sha256:")[:keyDigestLength]
+}
@@ -63,19 +63,28 @@ type RequestStatistics struct {
 	successCount  int64
 	failureCount  int64
 	totalTokens   int64
+	totalCost     float64
 
-	apis map[string]*apiStats
+	apis        map[string]*apiStats
+	credentials map[string]*credentialStats
 
 	requestsByDay  map[string]int64
 	requestsByHour map[int]int64
 	tokensByDay    map[string]int64
 	tokensByHour   map[int]int64
+
+	// maxDetails caps the request details kept per API key and model.
+	maxDetails int
 }
 
+// defaultMaxDetails is the number of request details kept per API key and model by default.
+const defaultMaxDetails = 1000
+
 // apiStats holds aggregated metrics for a single API key.
 type apiStats struct {
 	TotalRequests int64
 	TotalTokens   int64
+	TotalCost     float64
 	Models        map[string]*modelStats
 }
 
@@ -83,15 +92,27 @@ type apiStats struct {
 type modelStats struct {
 	TotalRequests int64
 	TotalTokens   int64
+	TotalCost     float64
 	Details       []RequestDetail
 }
 
+// credentialStats holds aggregated metrics for a single upstream credential.
+type credentialStats struct {
+	Provider string
+	Totals   CostTotals
+	Models   map[string]*CostTotals
+}
+
 // RequestDetail stores the timestamp and token usage for a single request.
 type RequestDetail struct {
 	Timestamp time.Time  `json:"timestamp"`
 	Source    string     `json:"source"`
 	Tokens    TokenStats `json:"tokens"`
 	Failed    bool       `json:"failed"`
+	// Partial marks a stream cut short before the provider reported usage; Tokens are estimated.
+	Partial bool `json:"partial,omitempty"`
+	// Cost is the list price of the tokens in US dollars; zero when the model has no price.
+	Cost float64 `json:"cost"`
 }
 
 // TokenStats captures the token usage breakdown for a request.
@@ -105,13 +126,17 @@ type TokenStats struct {
 
 // StatisticsSnapshot represents an immutable view of the aggregated metrics.
 type StatisticsSnapshot struct {
-	TotalRequests int64 `json:"total_requests"`
-	SuccessCount  int64 `json:"success_count"`
-	FailureCount  int64 `json:"failure_count"`
-	TotalTokens   int64 `json:"total_tokens"`
+	TotalRequests int64   `json:"total_requests"`
+	SuccessCount  int64   `json:"success_count"`
+	FailureCount  int64   `json:"failure_count"`
+	TotalTokens   int64   `json:"total_tokens"`
+	TotalCost     float64 `json:"total_cost"`
 
 	APIs map[string]APISnapshot `json:"apis"`
 
+	// Credentials summarises usage per upstream credential (auth ID).
+	Credentials map[string]CredentialSnapshot `json:"credentials"`
+
 	RequestsByDay  map[string]int64 `json:"requests_by_day"`
 	RequestsByHour map[string]int64 `json:"requests_by_hour"`
 	TokensByDay    map[string]int64 `json:"tokens_by_day"`
@@ -122,6 +147,7 @@ type StatisticsSnapshot struct {
 type APISnapshot struct {
 	TotalRequests int64                    `json:"total_requests"`
 	TotalTokens   int64                    `json:"total_tokens"`
+	TotalCost     float64                  `json:"total_cost"`
 	Models        map[string]ModelSnapshot `json:"models"`
 }
 
@@ -129,9 +155,30 @@ type APISnapshot struct {
 type ModelSnapshot struct {
 	TotalRequests int64           `json:"total_requests"`
 	TotalTokens   int64           `json:"total_tokens"`
+	TotalCost     float64         `json:"total_cost"`
 	Details       []RequestDetail `json:"details"`
 }
 
+// CostTotals counts requests, tokens and their cost.
+type CostTotals struct {
+	TotalRequests int64   `json:"total_requests"`
+	TotalTokens   int64   `json:"total_tokens"`
+	TotalCost     float64 `json:"total_cost"`
+}
+
+func (t *CostTotals) add(requests, tokens int64, cost float64) {
+	t.TotalRequests += requests
+	t.TotalTokens += tokens
+	t.TotalCost += cost
+}
+
+// CredentialSnapshot summarises metrics for a single upstream credential.
+type CredentialSnapshot struct {
+	Provider string `json:"provider,omitempty"`
+	CostTotals
+	Models map[string]CostTotals `json:"models"`
+}
+
 var defaultRequestStatistics = NewRequestStatistics()
 
 // GetRequestStatistics returns the shared statistics store.
@@ -141,10 +188,31 @@ func GetRequestStatistics() *RequestStatistics { return defaultRequestStatistics
 func NewRequestStatistics() *RequestStatistics {
 	return &RequestStatistics{
 		apis:           make(map[string]*apiStats),
+		credentials:    make(map[string]*credentialStats),
 		requestsByDay:  make(map[string]int64),
 		requestsByHour: make(map[int]int64),
 		tokensByDay:    make(map[string]int64),
 		tokensByHour:   make(map[int]int64),
+		maxDetails:     defaultMaxDetails,
+	}
+}
+
+// SetMaxDetails caps the request details kept per API key and model; older details are dropped.
+// Zero or negative values use the default of 1000.
+func (s *RequestStatistics) SetMaxDetails(n int) {
+	if s == nil {
+		return
+	}
+	if n <= 0 {
+		n = defaultMaxDetails
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxDetails = n
+	for _, stats := range s.apis {
+		for _, modelStatsValue := range stats.Models {
+			modelStatsValue.trim(n)
+		}
 	}
 }
 
@@ -162,15 +230,9 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 	}
 	detail := normaliseDetail(record.Detail)
 	totalTokens := detail.TotalTokens
-	statsKey := record.APIKey
-	if statsKey == "" {
-		statsKey = resolveAPIIdentifier(ctx, record)
-	}
-	failed := record.Failed
-	if !failed {
-		failed = !resolveSuccess(ctx)
-	}
+	statsKey, failed := recordIdentity(ctx, record)
 	success := !failed
+	cost := Prices().Cost(record.Provider, record.Model, detail)
 	modelName := record.Model
 	if modelName == "" {
 		modelName = "unknown"
@@ -188,6 +250,7 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 		s.failureCount++
 	}
 	s.totalTokens += totalTokens
+	s.totalCost += cost
 
 	stats, ok := s.apis[statsKey]
 	if !ok {
@@ -199,7 +262,10 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 		Source:    record.Source,
 		Tokens:    detail,
 		Failed:    failed,
+		Partial:   record.Partial,
+		Cost:      cost,
 	})
+	s.updateCredentialStats(record.AuthID, record.Provider, modelName, 1, totalTokens, cost)
 
 	s.requestsByDay[dayKey]++
 	s.requestsByHour[hourKey]++
@@ -210,6 +276,7 @@ func (s *RequestStatistics) Record(ctx context.Context, record coreusage.Record)
 func (s *RequestStatistics) updateAPIStats(stats *apiStats, model string, detail RequestDetail) {
 	stats.TotalRequests++
 	stats.TotalTokens += detail.Tokens.TotalTokens
+	stats.TotalCost += detail.Cost
 	modelStatsValue, ok := stats.Models[model]
 	if !ok {
 		modelStatsValue = &modelStats{}
@@ -217,7 +284,93 @@ func (s *RequestStatistics) updateAPIStats(stats *apiStats, model string, detail
 	}
 	modelStatsValue.TotalRequests++
 	modelStatsValue.TotalTokens += detail.Tokens.TotalTokens
+	modelStatsValue.TotalCost += detail.Cost
 	modelStatsValue.Details = append(modelStatsValue.Details, detail)
+	modelStatsValue.trim(s.maxDetails)
+}
+
+// updateCredentialStats adds usage to the credential totals; usage without a credential is skipped.
+func (s *RequestStatistics) updateCredentialStats(authID, provider, model string, requests, tokens int64, cost float64) {
+	if authID == "" {
+		return
+	}
+	stats, ok := s.credentials[authID]
+	if !ok {
+		stats = &credentialStats{Models: make(map[string]*CostTotals)}
+		s.credentials[authID] = stats
+	}
+	if provider != "" {
+		stats.Provider = provider
+	}
+	stats.Totals.add(requests, tokens, cost)
+	modelTotals, ok := stats.Models[model]
+	if !ok {
+		modelTotals = &CostTotals{}
+		stats.Models[model] = modelTotals
+	}
+	modelTotals.add(requests, tokens, cost)
+}
+
+// trim drops the oldest details beyond max.
+func (m *modelStats) trim(max int) {
+	if max <= 0 || len(m.Details) <= max {
+		return
+	}
+	n := copy(m.Details, m.Details[len(m.Details)-max:])
+	m.Details = m.Details[:n]
+}
+
+// Restore adds persisted hourly rollups to the aggregates so statistics survive restarts. Request
+// details are not restored, and costs are computed with the current prices.
+func (s *RequestStatistics) Restore(rollups []Rollup) {
+	if s == nil || len(rollups) == 0 {
+		return
+	}
+	prices := Prices()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rollup := range rollups {
+		tokens := rollup.Tokens.TotalTokens
+		cost := prices.Cost(rollup.Provider, rollup.Model, rollup.Tokens)
+		s.totalRequests += rollup.Requests
+		s.successCount += rollup.Requests - rollup.Failures
+		s.failureCount += rollup.Failures
+		s.totalTokens += tokens
+		s.totalCost += cost
+
+		statsKey := ClientIdentity(rollup.APIKey)
+		if statsKey == "" {
+			statsKey = "unknown"
+		}
+		modelName := rollup.Model
+		if modelName == "" {
+			modelName = "unknown"
+		}
+		stats, ok := s.apis[statsKey]
+		if !ok {
+			stats = &apiStats{Models: make(map[string]*modelStats)}
+			s.apis[statsKey] = stats
+		}
+		stats.TotalRequests += rollup.Requests
+		stats.TotalTokens += tokens
+		stats.TotalCost += cost
+		modelStatsValue, ok := stats.Models[modelName]
+		if !ok {
+			modelStatsValue = &modelStats{}
+			stats.Models[modelName] = modelStatsValue
+		}
+		modelStatsValue.TotalRequests += rollup.Requests
+		modelStatsValue.TotalTokens += tokens
+		modelStatsValue.TotalCost += cost
+		s.updateCredentialStats(rollup.AuthID, rollup.Provider, modelName, rollup.Requests, tokens, cost)
+
+		hour := rollup.Hour.Local()
+		dayKey := hour.Format("2006-01-02")
+		s.requestsByDay[dayKey] += rollup.Requests
+		s.requestsByHour[hour.Hour()] += rollup.Requests
+		s.tokensByDay[dayKey] += tokens
+		s.tokensByHour[hour.Hour()] += tokens
+	}
 }
 
 // Snapshot returns a copy of the aggregated metrics for external consumption.
@@ -234,12 +387,14 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 	result.SuccessCount = s.successCount
 	result.FailureCount = s.failureCount
 	result.TotalTokens = s.totalTokens
+	result.TotalCost = s.totalCost
 
 	result.APIs = make(map[string]APISnapshot, len(s.apis))
 	for apiName, stats := range s.apis {
 		apiSnapshot := APISnapshot{
 			TotalRequests: stats.TotalRequests,
 			TotalTokens:   stats.TotalTokens,
+			TotalCost:     stats.TotalCost,
 			Models:        make(map[string]ModelSnapshot, len(stats.Models)),
 		}
 		for modelName, modelStatsValue := range stats.Models {
@@ -248,12 +403,26 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 			apiSnapshot.Models[modelName] = ModelSnapshot{
 				TotalRequests: modelStatsValue.TotalRequests,
 				TotalTokens:   modelStatsValue.TotalTokens,
+				TotalCost:     modelStatsValue.TotalCost,
 				Details:       requestDetails,
 			}
 		}
 		result.APIs[apiName] = apiSnapshot
 	}
 
+	result.Credentials = make(map[string]CredentialSnapshot, len(s.credentials))
+	for authID, stats := range s.credentials {
+		credentialSnapshot := CredentialSnapshot{
+			Provider:   stats.Provider,
+			CostTotals: stats.Totals,
+			Models:     make(map[string]CostTotals, len(stats.Models)),
+		}
+		for modelName, totals := range stats.Models {
+			credentialSnapshot.Models[modelName] = *totals
+		}
+		result.Credentials[authID] = credentialSnapshot
+	}
+
 	result.RequestsByDay = make(map[string]int64, len(s.requestsByDay))
 	for k, v := range s.requestsByDay {
 		result.RequestsByDay[k] = v
@@ -279,6 +448,19 @@ func (s *RequestStatistics) Snapshot() StatisticsSnapshot {
 	return result
 }
 
+// recordIdentity returns the key a record is aggregated under and whether it failed.
+func recordIdentity(ctx context.Context, record coreusage.Record) (string, bool) {
+	statsKey := ClientIdentity(record.APIKey)
+	if statsKey == "" {
+		statsKey = resolveAPIIdentifier(ctx, record)
+	}
+	failed := record.Failed
+	if !failed {
+		failed = !resolveSuccess(ctx)
+	}
+	return statsKey, failed
+}
+
 func resolveAPIIdentifier(ctx context.Context, record coreusage.Record) string {
 	if ctx != nil {
 		if ginCtx, ok := ctx.Value("gin").(*gin.Context); ok && ginCtx != nil {
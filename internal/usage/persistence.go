@@ -0,0 +1,238 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	coreusage "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/usage"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultRetentionDays       = 30
+	defaultRollupRetentionDays = 365
+	storageFlushInterval       = 5 * time.Second
+	storagePruneInterval       = time.Hour
+	// storageMaxPending bounds the records held back while the store is failing.
+	storageMaxPending = 10000
+)
+
+// ErrStorageDisabled is returned by queries when no usage-storage type is configured.
+var ErrStorageDisabled = errors.New("usage storage is not configured")
+
+func init() {
+	coreusage.RegisterPlugin(defaultStorage)
+}
+
+// storage batches usage records into the configured Store and prunes it by retention.
+type storage struct {
+	mu              sync.Mutex
+	store           Store
+	storeKey        string
+	pending         []StoredRecord
+	retention       time.Duration
+	rollupRetention time.Duration
+	restored        bool
+	loopOnce        sync.Once
+	flushMu         sync.Mutex
+}
+
+var defaultStorage = &storage{}
+
+// ConfigureStorage applies the usage-storage settings. The store is reopened only when its type or
+// location changes; retention changes apply on the next prune. Persisted rollups are loaded into the
+// in-memory statistics the first time a store is opened, so they survive restarts.
+func ConfigureStorage(cfg config.UsageStorage, baseDir string) error {
+	defaultRequestStatistics.SetMaxDetails(cfg.MaxDetailsPerModel)
+	return defaultStorage.configure(cfg, baseDir)
+}
+
+// CloseStorage writes pending records and closes the store.
+func CloseStorage() {
+	s := defaultStorage
+	s.flush()
+	s.mu.Lock()
+	store := s.store
+	s.store = nil
+	s.storeKey = ""
+	s.mu.Unlock()
+	if store != nil {
+		if err := store.Close(); err != nil {
+			log.Errorf("usage storage: close: %v", err)
+		}
+	}
+}
+
+// QueryRecords returns stored records matching q, newest first.
+func QueryRecords(ctx context.Context, q Query) ([]StoredRecord, error) {
+	store := defaultStorage.current()
+	if store == nil {
+		return nil, ErrStorageDisabled
+	}
+	defaultStorage.flush()
+	return store.Records(ctx, q)
+}
+
+// QueryRollups returns stored hourly rollups matching q.
+func QueryRollups(ctx context.Context, q Query) ([]Rollup, error) {
+	store := defaultStorage.current()
+	if store == nil {
+		return nil, ErrStorageDisabled
+	}
+	defaultStorage.flush()
+	return store.Rollups(ctx, q)
+}
+
+func (s *storage) configure(cfg config.UsageStorage, baseDir string) error {
+	storageType := strings.ToLower(strings.TrimSpace(cfg.Type))
+	key := storageType + "\x00" + strings.TrimSpace(cfg.Dir) + "\x00" + baseDir
+	s.mu.Lock()
+	s.retention = retentionDays(cfg.RetentionDays, defaultRetentionDays)
+	s.rollupRetention = retentionDays(cfg.RollupRetentionDays, defaultRollupRetentionDays)
+	unchanged := key == s.storeKey || (storageType == "" && s.store == nil)
+	s.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	CloseStorage()
+	if storageType == "" {
+		return nil
+	}
+	store, err := openStore(cfg, baseDir)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.store = store
+	s.storeKey = key
+	restore := !s.restored
+	s.restored = true
+	rollupRetention := s.rollupRetention
+	s.mu.Unlock()
+
+	if restore {
+		rollups, errRollups := store.Rollups(context.Background(), Query{From: time.Now().Add(-rollupRetention)})
+		if errRollups != nil {
+			log.Errorf("usage storage: load rollups: %v", errRollups)
+		} else {
+			defaultRequestStatistics.Restore(rollups)
+			defaultBudgetTracker.Seed(rollups, time.Now())
+		}
+	}
+	s.loopOnce.Do(func() { go s.run() })
+	s.prune()
+	log.Infof("usage storage enabled (%s)", storageType)
+	return nil
+}
+
+func retentionDays(days, fallback int) time.Duration {
+	if days <= 0 {
+		days = fallback
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func (s *storage) current() Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store
+}
+
+// HandleUsage implements coreusage.Plugin by queueing the record for the next flush.
+func (s *storage) HandleUsage(ctx context.Context, record coreusage.Record) {
+	stored := storedRecordOf(ctx, record)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.store == nil {
+		return
+	}
+	s.pending = append(s.pending, stored)
+	if dropped := len(s.pending) - storageMaxPending; dropped > 0 {
+		s.pending = append(s.pending[:0], s.pending[dropped:]...)
+		log.Warnf("usage storage: dropped %d unwritten records", dropped)
+	}
+}
+
+func (s *storage) run() {
+	flushTicker := time.NewTicker(storageFlushInterval)
+	pruneTicker := time.NewTicker(storagePruneInterval)
+	defer flushTicker.Stop()
+	defer pruneTicker.Stop()
+	for {
+		select {
+		case <-flushTicker.C:
+			s.flush()
+		case <-pruneTicker.C:
+			s.prune()
+		}
+	}
+}
+
+// flush appends the pending records to the store. Records of failed batches that were not written
+// are retried on the next flush.
+func (s *storage) flush() {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	s.mu.Lock()
+	store := s.store
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	if store == nil || len(batch) == 0 {
+		return
+	}
+	if err := store.Append(context.Background(), batch); err != nil {
+		log.Errorf("usage storage: write %d records: %v", len(batch), err)
+		var appendErr *AppendError
+		if errors.As(err, &appendErr) {
+			batch = appendErr.Unwritten
+		}
+		if len(batch) == 0 {
+			return
+		}
+		s.mu.Lock()
+		if s.store == store {
+			s.pending = append(batch, s.pending...)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *storage) prune() {
+	s.mu.Lock()
+	store := s.store
+	retention, rollupRetention := s.retention, s.rollupRetention
+	s.mu.Unlock()
+	if store == nil {
+		return
+	}
+	now := time.Now()
+	if err := store.Prune(context.Background(), now.Add(-retention), now.Add(-rollupRetention)); err != nil {
+		log.Errorf("usage storage: prune: %v", err)
+	}
+}
+
+// storedRecordOf converts a runtime usage record with the identity the in-memory statistics use.
+func storedRecordOf(ctx context.Context, record coreusage.Record) StoredRecord {
+	apiKey, failed := recordIdentity(ctx, record)
+	timestamp := record.RequestedAt
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return StoredRecord{
+		Timestamp: timestamp.UTC(),
+		APIKey:    apiKey,
+		AuthID:    record.AuthID,
+		Provider:  record.Provider,
+		Model:     record.Model,
+		Source:    record.Source,
+		Failed:    failed,
+		Partial:   record.Partial,
+		Tokens:    normaliseDetail(record.Detail),
+	}
+}
@@ -0,0 +1,138 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	sdkconfig "github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
+	"gopkg.in/yaml.v3"
+)
+
+// tokensPerPriceUnit is the number of tokens prices are quoted for.
+const tokensPerPriceUnit = 1_000_000
+
+// PriceTable resolves model prices. Exact model names take precedence over patterns; among
+// patterns the first match wins.
+type PriceTable struct {
+	exact    map[string]config.ModelPrice
+	patterns []config.ModelPrice
+}
+
+var currentPrices atomic.Pointer[PriceTable]
+
+// NewPriceTable builds a table from price lists; entries of later lists replace those of earlier
+// lists for the same model.
+func NewPriceTable(lists ...[]config.ModelPrice) *PriceTable {
+	table := &PriceTable{exact: make(map[string]config.ModelPrice)}
+	patternIndex := make(map[string]int)
+	for _, list := range lists {
+		for _, price := range list {
+			key := strings.ToLower(strings.TrimSpace(price.Model))
+			if key == "" {
+				continue
+			}
+			if !strings.Contains(key, "*") {
+				table.exact[key] = price
+				continue
+			}
+			if i, ok := patternIndex[key]; ok {
+				table.patterns[i] = price
+				continue
+			}
+			patternIndex[key] = len(table.patterns)
+			table.patterns = append(table.patterns, price)
+		}
+	}
+	return table
+}
+
+// Lookup returns the price of model.
+func (t *PriceTable) Lookup(model string) (config.ModelPrice, bool) {
+	if t == nil {
+		return config.ModelPrice{}, false
+	}
+	if price, ok := t.exact[strings.ToLower(strings.TrimSpace(model))]; ok {
+		return price, true
+	}
+	for _, price := range t.patterns {
+		if sdkconfig.MatchModelPattern(price.Model, model) {
+			return price, true
+		}
+	}
+	return config.ModelPrice{}, false
+}
+
+// Cost returns the list price in US dollars of the tokens, or zero when the model has no price.
+//
+// Providers report tokens differently: Claude counts cache reads on top of input tokens while the
+// others include them in the input count, and OpenAI-style usage includes reasoning in the output
+// count while Gemini reports it separately. Cached and reasoning tokens are taken out of the input
+// and output counts where they are included, so every token is billed once.
+func (t *PriceTable) Cost(provider, model string, tokens TokenStats) float64 {
+	price, ok := t.Lookup(model)
+	if !ok {
+		return 0
+	}
+	cachedPrice := price.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = price.Input
+	}
+	reasoningPrice := price.Reasoning
+	if reasoningPrice == 0 {
+		reasoningPrice = price.Output
+	}
+
+	input := tokens.InputTokens
+	if !strings.EqualFold(provider, "claude") {
+		input = max(input-tokens.CachedTokens, 0)
+	}
+	output := tokens.OutputTokens
+	if tokens.TotalTokens < tokens.InputTokens+tokens.OutputTokens+tokens.ReasoningTokens {
+		output = max(output-tokens.ReasoningTokens, 0)
+	}
+	cost := float64(input)*price.Input +
+		float64(tokens.CachedTokens)*cachedPrice +
+		float64(output)*price.Output +
+		float64(tokens.ReasoningTokens)*reasoningPrice
+	return cost / tokensPerPriceUnit
+}
+
+// Prices returns the price table in use.
+func Prices() *PriceTable { return currentPrices.Load() }
+
+// ConfigurePricing loads the configured price file and applies the config overrides. On error the
+// previous table stays in use.
+func ConfigurePricing(cfg config.PricingConfig, baseDir string) error {
+	var filePrices []config.ModelPrice
+	if path := strings.TrimSpace(cfg.File); path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		var err error
+		if filePrices, err = LoadPriceFile(path); err != nil {
+			return err
+		}
+	}
+	currentPrices.Store(NewPriceTable(filePrices, cfg.Models))
+	return nil
+}
+
+// LoadPriceFile reads a YAML price file holding a "models" list in the format of the pricing
+// config block.
+func LoadPriceFile(path string) ([]config.ModelPrice, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("pricing: read %s: %w", path, err)
+	}
+	var file struct {
+		Models []config.ModelPrice `yaml:"models"`
+	}
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("pricing: parse %s: %w", path, err)
+	}
+	return file.Models, nil
+}
@@ -0,0 +1,282 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+)
+
+// StoredRecord is a usage record as kept by a Store.
+type StoredRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	// APIKey identifies the client as returned by ClientIdentity, never the key itself.
+	APIKey   string `json:"client-key,omitempty"`
+	AuthID   string `json:"auth-id,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Failed   bool   `json:"failed"`
+	// Partial marks a stream cut short before the provider reported usage; Tokens are estimated.
+	Partial bool       `json:"partial,omitempty"`
+	Tokens  TokenStats `json:"tokens"`
+}
+
+// Rollup aggregates the records of one hour that share client key, credential, provider and model.
+type Rollup struct {
+	Hour     time.Time  `json:"hour"`
+	APIKey   string     `json:"client-key,omitempty"`
+	AuthID   string     `json:"auth-id,omitempty"`
+	Provider string     `json:"provider,omitempty"`
+	Model    string     `json:"model,omitempty"`
+	Requests int64      `json:"requests"`
+	Failures int64      `json:"failures"`
+	Tokens   TokenStats `json:"tokens"`
+}
+
+// rollupKey identifies the rollup a record belongs to.
+type rollupKey struct {
+	hour     int64
+	apiKey   string
+	authID   string
+	provider string
+	model    string
+}
+
+func (r *Rollup) key() rollupKey {
+	return rollupKey{hour: r.Hour.Unix(), apiKey: r.APIKey, authID: r.AuthID, provider: r.Provider, model: r.Model}
+}
+
+// add counts record into the rollup.
+func (r *Rollup) add(record StoredRecord) {
+	r.Requests++
+	if record.Failed {
+		r.Failures++
+	}
+	r.Tokens.add(record.Tokens)
+}
+
+func (t *TokenStats) add(other TokenStats) {
+	t.InputTokens += other.InputTokens
+	t.OutputTokens += other.OutputTokens
+	t.ReasoningTokens += other.ReasoningTokens
+	t.CachedTokens += other.CachedTokens
+	t.TotalTokens += other.TotalTokens
+}
+
+// RollupsOf groups records into hourly rollups, the unit stores keep beyond record retention.
+func RollupsOf(records []StoredRecord) []Rollup {
+	index := make(map[rollupKey]int)
+	var out []Rollup
+	for _, record := range records {
+		rollup := Rollup{
+			Hour:     record.Timestamp.UTC().Truncate(time.Hour),
+			APIKey:   record.APIKey,
+			AuthID:   record.AuthID,
+			Provider: record.Provider,
+			Model:    record.Model,
+		}
+		key := rollup.key()
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, rollup)
+		}
+		out[i].add(record)
+	}
+	return out
+}
+
+// Query selects stored usage. Zero fields do not filter.
+type Query struct {
+	From     time.Time
+	To       time.Time
+	APIKey   string
+	AuthID   string
+	Provider string
+	Model    string
+	// Limit caps the number of records returned, newest first. It does not apply to rollups.
+	Limit int
+}
+
+// matches reports whether the dimensions match the query filters; the time range is checked by callers.
+func (q Query) matches(apiKey, authID, provider, model string) bool {
+	return (q.APIKey == "" || q.APIKey == apiKey) &&
+		(q.AuthID == "" || q.AuthID == authID) &&
+		(q.Provider == "" || strings.EqualFold(q.Provider, provider)) &&
+		(q.Model == "" || q.Model == model)
+}
+
+// Store persists usage records together with their hourly rollups.
+type Store interface {
+	// Append persists records and adds them to the rollups. A batch that is written only in part
+	// fails with an AppendError naming the records still unwritten.
+	Append(ctx context.Context, records []StoredRecord) error
+	// Records returns the records in [From, To) matching the query, newest first.
+	Records(ctx context.Context, q Query) ([]StoredRecord, error)
+	// Rollups returns the hourly rollups in [From, To) matching the query.
+	Rollups(ctx context.Context, q Query) ([]Rollup, error)
+	// Prune deletes records older than recordsBefore and rollups older than rollupsBefore.
+	Prune(ctx context.Context, recordsBefore, rollupsBefore time.Time) error
+	// Close flushes and releases the store.
+	Close() error
+}
+
+// AppendError reports a batch a Store only partly wrote. Unwritten holds the records that were not
+// stored and may be appended again; the rest of the batch must not be.
+type AppendError struct {
+	Unwritten []StoredRecord
+	Err       error
+}
+
+func (e *AppendError) Error() string { return e.Err.Error() }
+
+func (e *AppendError) Unwrap() error { return e.Err }
+
+// StoreOpener opens the Store for a usage-storage configuration. baseDir is the directory relative
+// paths resolve against.
+type StoreOpener func(cfg config.UsageStorage, baseDir string) (Store, error)
+
+var (
+	storeBackendsMu sync.RWMutex
+	storeBackends   = map[string]StoreOpener{
+		config.UsageStorageFile: openFileStore,
+	}
+)
+
+// RegisterStoreBackend makes a usage-storage type available. The file type is built in; the
+// postgres type is registered when the Postgres-backed store is in use.
+func RegisterStoreBackend(storageType string, open StoreOpener) {
+	storageType = strings.ToLower(strings.TrimSpace(storageType))
+	if storageType == "" || open == nil {
+		return
+	}
+	storeBackendsMu.Lock()
+	storeBackends[storageType] = open
+	storeBackendsMu.Unlock()
+}
+
+func openStore(cfg config.UsageStorage, baseDir string) (Store, error) {
+	storageType := strings.ToLower(strings.TrimSpace(cfg.Type))
+	storeBackendsMu.RLock()
+	open := storeBackends[storageType]
+	storeBackendsMu.RUnlock()
+	if open == nil {
+		return nil, fmt.Errorf("usage storage: unsupported type %q", cfg.Type)
+	}
+	return open(cfg, baseDir)
+}
+
+// Summary intervals.
+const (
+	IntervalHour  = "hour"
+	IntervalDay   = "day"
+	IntervalTotal = "total"
+)
+
+// Summary dimensions.
+const (
+	DimensionClientKey = "client-key"
+	DimensionAuthID    = "auth-id"
+	DimensionProvider  = "provider"
+	DimensionModel     = "model"
+)
+
+// ValidDimension reports whether name can be used to group a summary.
+func ValidDimension(name string) bool {
+	switch name {
+	case DimensionClientKey, DimensionAuthID, DimensionProvider, DimensionModel:
+		return true
+	}
+	return false
+}
+
+// SummaryBucket aggregates rollups of one interval and dimension combination. Dimensions that are
+// not grouped on are left empty.
+type SummaryBucket struct {
+	Start    *time.Time `json:"start,omitempty"`
+	APIKey   string     `json:"client-key,omitempty"`
+	AuthID   string     `json:"auth-id,omitempty"`
+	Provider string     `json:"provider,omitempty"`
+	Model    string     `json:"model,omitempty"`
+	Requests int64      `json:"requests"`
+	Failures int64      `json:"failures"`
+	Tokens   TokenStats `json:"tokens"`
+	// Cost is the list price of the tokens in US dollars at the current prices.
+	Cost float64 `json:"cost"`
+}
+
+// Summarize groups rollups by interval (hour, day in loc, or total) and the given dimensions.
+// Buckets are ordered by start time and then by dimension values.
+func Summarize(rollups []Rollup, interval string, groupBy []string, loc *time.Location) []SummaryBucket {
+	if loc == nil {
+		loc = time.Local
+	}
+	prices := Prices()
+	grouped := make(map[string]bool, len(groupBy))
+	for _, name := range groupBy {
+		grouped[name] = true
+	}
+	index := make(map[rollupKey]int)
+	var out []SummaryBucket
+	for _, rollup := range rollups {
+		var key rollupKey
+		var start *time.Time
+		switch interval {
+		case IntervalHour:
+			hour := rollup.Hour.In(loc)
+			start = &hour
+		case IntervalDay:
+			hour := rollup.Hour.In(loc)
+			day := time.Date(hour.Year(), hour.Month(), hour.Day(), 0, 0, 0, 0, loc)
+			start = &day
+		}
+		if start != nil {
+			key.hour = start.Unix()
+		}
+		if grouped[DimensionClientKey] {
+			key.apiKey = rollup.APIKey
+		}
+		if grouped[DimensionAuthID] {
+			key.authID = rollup.AuthID
+		}
+		if grouped[DimensionProvider] {
+			key.provider = rollup.Provider
+		}
+		if grouped[DimensionModel] {
+			key.model = rollup.Model
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(out)
+			index[key] = i
+			out = append(out, SummaryBucket{Start: start, APIKey: key.apiKey, AuthID: key.authID, Provider: key.provider, Model: key.model})
+		}
+		out[i].Requests += rollup.Requests
+		out[i].Failures += rollup.Failures
+		out[i].Tokens.add(rollup.Tokens)
+		out[i].Cost += prices.Cost(rollup.Provider, rollup.Model, rollup.Tokens)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Start != nil && b.Start != nil && !a.Start.Equal(*b.Start) {
+			return a.Start.Before(*b.Start)
+		}
+		if a.APIKey != b.APIKey {
+			return a.APIKey < b.APIKey
+		}
+		if a.AuthID != b.AuthID {
+			return a.AuthID < b.AuthID
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Model < b.Model
+	})
+	return out
+}
@@ -0,0 +1,94 @@
+package watcher
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SetTLSReloadCallback registers the function called after the TLS certificate, key or client CA
+// files change on disk.
+func (w *Watcher) SetTLSReloadCallback(fn func()) {
+	w.tlsMu.Lock()
+	defer w.tlsMu.Unlock()
+	w.tlsReloadCallback = fn
+}
+
+// watchTLSFiles watches the directories of the TLS files configured at startup. Directories rather
+// than files are watched so that atomic replacements and Kubernetes secret symlink swaps are seen.
+func (w *Watcher) watchTLSFiles() {
+	w.clientsMutex.RLock()
+	cfg := w.config
+	w.clientsMutex.RUnlock()
+	if cfg == nil || !cfg.TLS.Enable {
+		return
+	}
+	files := make(map[string]struct{})
+	dirs := make(map[string]struct{})
+	for _, path := range []string{cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile} {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		abs, errAbs := filepath.Abs(path)
+		if errAbs != nil {
+			log.Errorf("failed to resolve TLS file %s: %v", path, errAbs)
+			continue
+		}
+		files[abs] = struct{}{}
+		dir := filepath.Dir(abs)
+		if _, seen := dirs[dir]; seen {
+			continue
+		}
+		if errAdd := w.watcher.Add(dir); errAdd != nil {
+			log.Errorf("failed to watch TLS directory %s: %v", dir, errAdd)
+			continue
+		}
+		dirs[dir] = struct{}{}
+		log.Debugf("watching TLS directory: %s", dir)
+	}
+	w.tlsMu.Lock()
+	w.tlsFiles = files
+	w.tlsDirs = dirs
+	w.tlsMu.Unlock()
+}
+
+// isTLSEvent reports whether name is a watched TLS file or the "..data"-style symlink through
+// which Kubernetes swaps mounted secrets.
+func (w *Watcher) isTLSEvent(name string) bool {
+	abs, errAbs := filepath.Abs(name)
+	if errAbs != nil {
+		return false
+	}
+	w.tlsMu.Lock()
+	defer w.tlsMu.Unlock()
+	if _, ok := w.tlsFiles[abs]; ok {
+		return true
+	}
+	if _, ok := w.tlsDirs[filepath.Dir(abs)]; ok {
+		return strings.HasPrefix(filepath.Base(abs), "..")
+	}
+	return false
+}
+
+// scheduleTLSReload calls the TLS reload callback once the files have been quiet for tlsReloadDelay.
+func (w *Watcher) scheduleTLSReload() {
+	w.tlsMu.Lock()
+	defer w.tlsMu.Unlock()
+	if w.tlsReloadCallback == nil {
+		return
+	}
+	if w.tlsReloadTimer != nil {
+		w.tlsReloadTimer.Stop()
+	}
+	w.tlsReloadTimer = time.AfterFunc(tlsReloadDelay, func() {
+		w.tlsMu.Lock()
+		callback := w.tlsReloadCallback
+		w.tlsMu.Unlock()
+		if callback != nil {
+			callback()
+		}
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -59,6 +60,12 @@ type Watcher struct {
 	storePersister  storePersister
 	mirroredAuthDir string
 	oldConfigYaml   []byte
+
+	tlsMu             sync.Mutex
+	tlsFiles          map[string]struct{}
+	tlsDirs           map[string]struct{}
+	tlsReloadCallback func()
+	tlsReloadTimer    *time.Timer
 }
 
 type stableIDGenerator struct {
@@ -114,6 +121,9 @@ const (
 	// replaceCheckDelay is a short delay to allow atomic replace (rename) to settle
 	// before deciding whether a Remove event indicates a real deletion.
 	replaceCheckDelay = 50 * time.Millisecond
+	// tlsReloadDelay batches the writes of a certificate renewal, which usually touches the
+	// certificate and key separately, into one reload.
+	tlsReloadDelay = 500 * time.Millisecond
 )
 
 // NewWatcher creates a new file watcher instance
@@ -161,6 +171,9 @@ func (w *Watcher) Start(ctx context.Context) error {
 	}
 	log.Debugf("watching auth directory: %s", w.authDir)
 
+	// Watch the TLS certificate files so renewals apply without a restart
+	w.watchTLSFiles()
+
 	// Start the event processing goroutine
 	go w.processEvents(ctx)
 
@@ -423,6 +436,54 @@ func computeOpenAICompatModelsHash(models []config.OpenAICompatibilityModel) str
 	return hex.EncodeToString(sum[:])
 }
 
+// addSchedulingAttributes records non-zero priority, weight and concurrency settings for the routing selectors.
+func addSchedulingAttributes(attrs map[string]string, priority, weight, maxConcurrency int) {
+	if priority != 0 {
+		attrs[coreauth.AttributePriority] = strconv.Itoa(priority)
+	}
+	if weight != 0 {
+		attrs[coreauth.AttributeWeight] = strconv.Itoa(weight)
+	}
+	if maxConcurrency > 0 {
+		attrs[coreauth.AttributeMaxConcurrency] = strconv.Itoa(maxConcurrency)
+	}
+}
+
+// addRateLimitAttributes records non-zero requests- and tokens-per-minute caps for the rate limiter.
+func addRateLimitAttributes(attrs map[string]string, rpm, tpm int) {
+	if rpm > 0 {
+		attrs[coreauth.AttributeRPM] = strconv.Itoa(rpm)
+	}
+	if tpm > 0 {
+		attrs[coreauth.AttributeTPM] = strconv.Itoa(tpm)
+	}
+}
+
+// addGroupAttribute records the credential groups of a config-defined credential.
+func addGroupAttribute(attrs map[string]string, group string) {
+	if group = strings.TrimSpace(group); group != "" {
+		attrs[coreauth.AttributeGroup] = group
+	}
+}
+
+func firstNonZero(values ...int) int {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // computeClaudeModelsHash returns a stable hash for Claude model aliases.
 func computeClaudeModelsHash(models []config.ClaudeModel) string {
 	if len(models) == 0 {
@@ -462,6 +523,12 @@ func (w *Watcher) processEvents(ctx context.Context) {
 
 // handleEvent processes individual file system events
 func (w *Watcher) handleEvent(event fsnotify.Event) {
+	if w.isTLSEvent(event.Name) {
+		log.Debugf("TLS file event detected: %s %s", event.Op.String(), event.Name)
+		w.scheduleTLSReload()
+		return
+	}
+
 	// Filter only relevant events: config file or auth-dir JSON files.
 	isConfigEvent := event.Name == w.configPath && (event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create)
 	isAuthJSON := strings.HasPrefix(event.Name, w.authDir) && strings.HasSuffix(event.Name, ".json")
@@ -746,26 +813,37 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 	w.clientsMutex.RUnlock()
 	if cfg != nil {
 		// Gemini official API keys -> synthesize auths
-		for i := range cfg.GlAPIKey {
-			k := strings.TrimSpace(cfg.GlAPIKey[i])
+		addGeminiKey := func(key, group string) {
+			k := strings.TrimSpace(key)
 			if k == "" {
-				continue
+				return
 			}
 			id, token := idGen.next("gemini:apikey", k)
+			attrs := map[string]string{
+				"source":  fmt.Sprintf("config:gemini[%s]", token),
+				"api_key": k,
+			}
+			addGroupAttribute(attrs, group)
 			a := &coreauth.Auth{
-				ID:       id,
-				Provider: "gemini",
-				Label:    "gemini-apikey",
-				Status:   coreauth.StatusActive,
-				Attributes: map[string]string{
-					"source":  fmt.Sprintf("config:gemini[%s]", token),
-					"api_key": k,
-				},
-				CreatedAt: now,
-				UpdatedAt: now,
+				ID:         id,
+				Provider:   "gemini",
+				Label:      "gemini-apikey",
+				Status:     coreauth.StatusActive,
+				Attributes: attrs,
+				CreatedAt:  now,
+				UpdatedAt:  now,
 			}
 			out = append(out, a)
 		}
+		for i := range cfg.GlAPIKey {
+			addGeminiKey(cfg.GlAPIKey[i], "")
+		}
+		for i := range cfg.GlAPIKeyGroups {
+			entry := cfg.GlAPIKeyGroups[i]
+			for j := range entry.APIKeys {
+				addGeminiKey(entry.APIKeys[j], entry.Group)
+			}
+		}
 		// Claude API keys -> synthesize auths
 		for i := range cfg.ClaudeKey {
 			ck := cfg.ClaudeKey[i]
@@ -785,6 +863,9 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 			if hash := computeClaudeModelsHash(ck.Models); hash != "" {
 				attrs["models_hash"] = hash
 			}
+			addSchedulingAttributes(attrs, ck.Priority, ck.Weight, ck.MaxConcurrency)
+			addRateLimitAttributes(attrs, ck.RPM, ck.TPM)
+			addGroupAttribute(attrs, ck.Group)
 			proxyURL := strings.TrimSpace(ck.ProxyURL)
 			a := &coreauth.Auth{
 				ID:         id,
@@ -813,6 +894,9 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 			if ck.BaseURL != "" {
 				attrs["base_url"] = ck.BaseURL
 			}
+			addSchedulingAttributes(attrs, ck.Priority, ck.Weight, ck.MaxConcurrency)
+			addRateLimitAttributes(attrs, ck.RPM, ck.TPM)
+			addGroupAttribute(attrs, ck.Group)
 			proxyURL := strings.TrimSpace(ck.ProxyURL)
 			a := &coreauth.Auth{
 				ID:         id,
@@ -855,6 +939,9 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 					if hash := computeOpenAICompatModelsHash(compat.Models); hash != "" {
 						attrs["models_hash"] = hash
 					}
+					addSchedulingAttributes(attrs, firstNonZero(entry.Priority, compat.Priority), firstNonZero(entry.Weight, compat.Weight), firstNonZero(entry.MaxConcurrency, compat.MaxConcurrency))
+					addRateLimitAttributes(attrs, firstNonZero(entry.RPM, compat.RPM), firstNonZero(entry.TPM, compat.TPM))
+					addGroupAttribute(attrs, firstNonEmpty(entry.Group, compat.Group))
 					a := &coreauth.Auth{
 						ID:         id,
 						Provider:   providerName,
@@ -887,6 +974,9 @@ func (w *Watcher) SnapshotCoreAuths() []*coreauth.Auth {
 					if hash := computeOpenAICompatModelsHash(compat.Models); hash != "" {
 						attrs["models_hash"] = hash
 					}
+					addSchedulingAttributes(attrs, compat.Priority, compat.Weight, compat.MaxConcurrency)
+					addRateLimitAttributes(attrs, compat.RPM, compat.TPM)
+					addGroupAttribute(attrs, compat.Group)
 					a := &coreauth.Auth{
 						ID:         id,
 						Provider:   providerName,
@@ -1039,6 +1129,9 @@ func BuildAPIKeyClients(cfg *config.Config) (int, int, int, int) {
 		// Stateless executor handles Gemini API keys; avoid constructing legacy clients.
 		glAPIKeyCount += len(cfg.GlAPIKey)
 	}
+	for i := range cfg.GlAPIKeyGroups {
+		glAPIKeyCount += len(cfg.GlAPIKeyGroups[i].APIKeys)
+	}
 	if len(cfg.ClaudeKey) > 0 {
 		claudeAPIKeyCount += len(cfg.ClaudeKey)
 	}
@@ -1120,6 +1213,24 @@ func describeOpenAICompatibilityUpdate(oldEntry, newEntry config.OpenAICompatibi
 	if oldModelCount != newModelCount {
 		details = append(details, fmt.Sprintf("models %d -> %d", oldModelCount, newModelCount))
 	}
+	if oldEntry.Priority != newEntry.Priority {
+		details = append(details, fmt.Sprintf("priority %d -> %d", oldEntry.Priority, newEntry.Priority))
+	}
+	if oldEntry.Weight != newEntry.Weight {
+		details = append(details, fmt.Sprintf("weight %d -> %d", oldEntry.Weight, newEntry.Weight))
+	}
+	if oldEntry.MaxConcurrency != newEntry.MaxConcurrency {
+		details = append(details, fmt.Sprintf("max-concurrency %d -> %d", oldEntry.MaxConcurrency, newEntry.MaxConcurrency))
+	}
+	if oldEntry.RPM != newEntry.RPM {
+		details = append(details, fmt.Sprintf("rpm %d -> %d", oldEntry.RPM, newEntry.RPM))
+	}
+	if oldEntry.TPM != newEntry.TPM {
+		details = append(details, fmt.Sprintf("tpm %d -> %d", oldEntry.TPM, newEntry.TPM))
+	}
+	if strings.TrimSpace(oldEntry.Group) != strings.TrimSpace(newEntry.Group) {
+		details = append(details, fmt.Sprintf("group %s -> %s", strings.TrimSpace(oldEntry.Group), strings.TrimSpace(newEntry.Group)))
+	}
 	if len(details) == 0 {
 		return ""
 	}
@@ -1197,6 +1308,12 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.Port != newCfg.Port {
 		changes = append(changes, fmt.Sprintf("port: %d -> %d", oldCfg.Port, newCfg.Port))
 	}
+	if oldCfg.TLS != newCfg.TLS {
+		changes = append(changes, fmt.Sprintf("tls: updated (enable %t -> %t, applies after restart; certificate file contents reload automatically)", oldCfg.TLS.Enable, newCfg.TLS.Enable))
+	}
+	if !reflect.DeepEqual(oldCfg.TrustedProxies, newCfg.TrustedProxies) {
+		changes = append(changes, fmt.Sprintf("trusted-proxies: %d -> %d entries", len(oldCfg.TrustedProxies), len(newCfg.TrustedProxies)))
+	}
 	if oldCfg.AuthDir != newCfg.AuthDir {
 		changes = append(changes, fmt.Sprintf("auth-dir: %s -> %s", oldCfg.AuthDir, newCfg.AuthDir))
 	}
@@ -1218,6 +1335,84 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	if oldCfg.RequestRetry != newCfg.RequestRetry {
 		changes = append(changes, fmt.Sprintf("request-retry: %d -> %d", oldCfg.RequestRetry, newCfg.RequestRetry))
 	}
+	if oldCfg.RetryPolicy.InitialBackoffMS != newCfg.RetryPolicy.InitialBackoffMS {
+		changes = append(changes, fmt.Sprintf("retry-policy.initial-backoff-ms: %d -> %d", oldCfg.RetryPolicy.InitialBackoffMS, newCfg.RetryPolicy.InitialBackoffMS))
+	}
+	if oldCfg.RetryPolicy.MaxBackoffMS != newCfg.RetryPolicy.MaxBackoffMS {
+		changes = append(changes, fmt.Sprintf("retry-policy.max-backoff-ms: %d -> %d", oldCfg.RetryPolicy.MaxBackoffMS, newCfg.RetryPolicy.MaxBackoffMS))
+	}
+	if !reflect.DeepEqual(oldCfg.RetryPolicy.StatusCodes, newCfg.RetryPolicy.StatusCodes) {
+		changes = append(changes, fmt.Sprintf("retry-policy.status-codes: %v -> %v", oldCfg.RetryPolicy.StatusCodes, newCfg.RetryPolicy.StatusCodes))
+	}
+	if !reflect.DeepEqual(oldCfg.RetryPolicy.Providers, newCfg.RetryPolicy.Providers) {
+		changes = append(changes, fmt.Sprintf("retry-policy.providers count: %d -> %d", len(oldCfg.RetryPolicy.Providers), len(newCfg.RetryPolicy.Providers)))
+	}
+	if oldCfg.Routing.Strategy != newCfg.Routing.Strategy {
+		changes = append(changes, fmt.Sprintf("routing.strategy: %s -> %s", oldCfg.Routing.Strategy, newCfg.Routing.Strategy))
+	}
+	if oldCfg.Routing.AffinityTTLSeconds != newCfg.Routing.AffinityTTLSeconds {
+		changes = append(changes, fmt.Sprintf("routing.affinity-ttl-seconds: %d -> %d", oldCfg.Routing.AffinityTTLSeconds, newCfg.Routing.AffinityTTLSeconds))
+	}
+	if oldCfg.Routing.ConcurrencyWaitMS != newCfg.Routing.ConcurrencyWaitMS {
+		changes = append(changes, fmt.Sprintf("routing.concurrency-wait-ms: %d -> %d", oldCfg.Routing.ConcurrencyWaitMS, newCfg.Routing.ConcurrencyWaitMS))
+	}
+	if !reflect.DeepEqual(oldCfg.ModelFallbacks, newCfg.ModelFallbacks) {
+		changes = append(changes, fmt.Sprintf("model-fallbacks count: %d -> %d", len(oldCfg.ModelFallbacks), len(newCfg.ModelFallbacks)))
+	}
+	if !reflect.DeepEqual(oldCfg.RateLimits, newCfg.RateLimits) {
+		changes = append(changes, fmt.Sprintf("rate-limits count: %d -> %d", len(oldCfg.RateLimits), len(newCfg.RateLimits)))
+	}
+	if oldCfg.CircuitBreaker.Disable != newCfg.CircuitBreaker.Disable {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.disable: %t -> %t", oldCfg.CircuitBreaker.Disable, newCfg.CircuitBreaker.Disable))
+	}
+	if oldCfg.CircuitBreaker.FailureThreshold != newCfg.CircuitBreaker.FailureThreshold {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.failure-threshold: %d -> %d", oldCfg.CircuitBreaker.FailureThreshold, newCfg.CircuitBreaker.FailureThreshold))
+	}
+	if oldCfg.CircuitBreaker.ErrorRate != newCfg.CircuitBreaker.ErrorRate {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.error-rate: %g -> %g", oldCfg.CircuitBreaker.ErrorRate, newCfg.CircuitBreaker.ErrorRate))
+	}
+	if oldCfg.CircuitBreaker.MinRequests != newCfg.CircuitBreaker.MinRequests {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.min-requests: %d -> %d", oldCfg.CircuitBreaker.MinRequests, newCfg.CircuitBreaker.MinRequests))
+	}
+	if oldCfg.CircuitBreaker.WindowSeconds != newCfg.CircuitBreaker.WindowSeconds {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.window-seconds: %d -> %d", oldCfg.CircuitBreaker.WindowSeconds, newCfg.CircuitBreaker.WindowSeconds))
+	}
+	if oldCfg.CircuitBreaker.CooldownSeconds != newCfg.CircuitBreaker.CooldownSeconds {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.cooldown-seconds: %d -> %d", oldCfg.CircuitBreaker.CooldownSeconds, newCfg.CircuitBreaker.CooldownSeconds))
+	}
+	if oldCfg.CircuitBreaker.MaxCooldownSeconds != newCfg.CircuitBreaker.MaxCooldownSeconds {
+		changes = append(changes, fmt.Sprintf("circuit-breaker.max-cooldown-seconds: %d -> %d", oldCfg.CircuitBreaker.MaxCooldownSeconds, newCfg.CircuitBreaker.MaxCooldownSeconds))
+	}
+	if oldCfg.ClientLimits.RPM != newCfg.ClientLimits.RPM {
+		changes = append(changes, fmt.Sprintf("client-limits.rpm: %d -> %d", oldCfg.ClientLimits.RPM, newCfg.ClientLimits.RPM))
+	}
+	if oldCfg.ClientLimits.MaxConcurrentStreams != newCfg.ClientLimits.MaxConcurrentStreams {
+		changes = append(changes, fmt.Sprintf("client-limits.max-concurrent-streams: %d -> %d", oldCfg.ClientLimits.MaxConcurrentStreams, newCfg.ClientLimits.MaxConcurrentStreams))
+	}
+	if oldCfg.ClientLimits.PerIPRPM != newCfg.ClientLimits.PerIPRPM {
+		changes = append(changes, fmt.Sprintf("client-limits.per-ip-rpm: %d -> %d", oldCfg.ClientLimits.PerIPRPM, newCfg.ClientLimits.PerIPRPM))
+	}
+	if oldCfg.ClientLimits.PerIPMaxConcurrentStreams != newCfg.ClientLimits.PerIPMaxConcurrentStreams {
+		changes = append(changes, fmt.Sprintf("client-limits.per-ip-max-concurrent-streams: %d -> %d", oldCfg.ClientLimits.PerIPMaxConcurrentStreams, newCfg.ClientLimits.PerIPMaxConcurrentStreams))
+	}
+	if oldCfg.UsageStorage != newCfg.UsageStorage {
+		changes = append(changes, fmt.Sprintf("usage-storage: updated (type %q -> %q)", oldCfg.UsageStorage.Type, newCfg.UsageStorage.Type))
+	}
+	if !reflect.DeepEqual(oldCfg.CORS, newCfg.CORS) {
+		changes = append(changes, fmt.Sprintf("cors: updated (allowed-origins %d -> %d)", len(oldCfg.CORS.AllowedOrigins), len(newCfg.CORS.AllowedOrigins)))
+	}
+	if !reflect.DeepEqual(oldCfg.UsageExport, newCfg.UsageExport) {
+		changes = append(changes, fmt.Sprintf("usage-export: updated (url %q -> %q)", oldCfg.UsageExport.URL, newCfg.UsageExport.URL))
+	}
+	if !reflect.DeepEqual(oldCfg.Pricing, newCfg.Pricing) {
+		changes = append(changes, fmt.Sprintf("pricing: updated (file %q -> %q, models %d -> %d)", oldCfg.Pricing.File, newCfg.Pricing.File, len(oldCfg.Pricing.Models), len(newCfg.Pricing.Models)))
+	}
+	if oldCfg.Metrics.Enable != newCfg.Metrics.Enable {
+		changes = append(changes, fmt.Sprintf("metrics.enable: %t -> %t", oldCfg.Metrics.Enable, newCfg.Metrics.Enable))
+	}
+	if oldCfg.Metrics.BearerToken != newCfg.Metrics.BearerToken {
+		changes = append(changes, "metrics.bearer-token: updated")
+	}
 	if oldCfg.ProxyURL != newCfg.ProxyURL {
 		changes = append(changes, fmt.Sprintf("proxy-url: %s -> %s", oldCfg.ProxyURL, newCfg.ProxyURL))
 	}
@@ -1239,11 +1434,21 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 	} else if !reflect.DeepEqual(trimStrings(oldCfg.APIKeys), trimStrings(newCfg.APIKeys)) {
 		changes = append(changes, "api-keys: values updated (count unchanged, redacted)")
 	}
+	if len(oldCfg.ClientKeys) != len(newCfg.ClientKeys) {
+		changes = append(changes, fmt.Sprintf("client-keys count: %d -> %d", len(oldCfg.ClientKeys), len(newCfg.ClientKeys)))
+	} else if !reflect.DeepEqual(oldCfg.ClientKeys, newCfg.ClientKeys) {
+		changes = append(changes, "client-keys: entries updated (count unchanged, redacted)")
+	}
 	if len(oldCfg.GlAPIKey) != len(newCfg.GlAPIKey) {
 		changes = append(changes, fmt.Sprintf("generative-language-api-key count: %d -> %d", len(oldCfg.GlAPIKey), len(newCfg.GlAPIKey)))
 	} else if !reflect.DeepEqual(trimStrings(oldCfg.GlAPIKey), trimStrings(newCfg.GlAPIKey)) {
 		changes = append(changes, "generative-language-api-key: values updated (count unchanged, redacted)")
 	}
+	if len(oldCfg.GlAPIKeyGroups) != len(newCfg.GlAPIKeyGroups) {
+		changes = append(changes, fmt.Sprintf("generative-language-api-key-groups count: %d -> %d", len(oldCfg.GlAPIKeyGroups), len(newCfg.GlAPIKeyGroups)))
+	} else if !reflect.DeepEqual(oldCfg.GlAPIKeyGroups, newCfg.GlAPIKeyGroups) {
+		changes = append(changes, "generative-language-api-key-groups: entries updated (count unchanged, redacted)")
+	}
 
 	// Claude keys (do not print key material)
 	if len(oldCfg.ClaudeKey) != len(newCfg.ClaudeKey) {
@@ -1264,6 +1469,24 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if strings.TrimSpace(o.APIKey) != strings.TrimSpace(n.APIKey) {
 				changes = append(changes, fmt.Sprintf("claude[%d].api-key: updated", i))
 			}
+			if o.Priority != n.Priority {
+				changes = append(changes, fmt.Sprintf("claude[%d].priority: %d -> %d", i, o.Priority, n.Priority))
+			}
+			if o.Weight != n.Weight {
+				changes = append(changes, fmt.Sprintf("claude[%d].weight: %d -> %d", i, o.Weight, n.Weight))
+			}
+			if o.MaxConcurrency != n.MaxConcurrency {
+				changes = append(changes, fmt.Sprintf("claude[%d].max-concurrency: %d -> %d", i, o.MaxConcurrency, n.MaxConcurrency))
+			}
+			if o.RPM != n.RPM {
+				changes = append(changes, fmt.Sprintf("claude[%d].rpm: %d -> %d", i, o.RPM, n.RPM))
+			}
+			if o.TPM != n.TPM {
+				changes = append(changes, fmt.Sprintf("claude[%d].tpm: %d -> %d", i, o.TPM, n.TPM))
+			}
+			if strings.TrimSpace(o.Group) != strings.TrimSpace(n.Group) {
+				changes = append(changes, fmt.Sprintf("claude[%d].group: %s -> %s", i, strings.TrimSpace(o.Group), strings.TrimSpace(n.Group)))
+			}
 		}
 	}
 
@@ -1286,6 +1509,24 @@ func buildConfigChangeDetails(oldCfg, newCfg *config.Config) []string {
 			if strings.TrimSpace(o.APIKey) != strings.TrimSpace(n.APIKey) {
 				changes = append(changes, fmt.Sprintf("codex[%d].api-key: updated", i))
 			}
+			if o.Priority != n.Priority {
+				changes = append(changes, fmt.Sprintf("codex[%d].priority: %d -> %d", i, o.Priority, n.Priority))
+			}
+			if o.Weight != n.Weight {
+				changes = append(changes, fmt.Sprintf("codex[%d].weight: %d -> %d", i, o.Weight, n.Weight))
+			}
+			if o.MaxConcurrency != n.MaxConcurrency {
+				changes = append(changes, fmt.Sprintf("codex[%d].max-concurrency: %d -> %d", i, o.MaxConcurrency, n.MaxConcurrency))
+			}
+			if o.RPM != n.RPM {
+				changes = append(changes, fmt.Sprintf("codex[%d].rpm: %d -> %d", i, o.RPM, n.RPM))
+			}
+			if o.TPM != n.TPM {
+				changes = append(changes, fmt.Sprintf("codex[%d].tpm: %d -> %d", i, o.TPM, n.TPM))
+			}
+			if strings.TrimSpace(o.Group) != strings.TrimSpace(n.Group) {
+				changes = append(changes, fmt.Sprintf("codex[%d].group: %s -> %s", i, strings.TrimSpace(o.Group), strings.TrimSpace(n.Group)))
+			}
 		}
 	}
 
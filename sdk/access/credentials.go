@@ -0,0 +1,55 @@
+package access
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Credential is a client credential found in a request together with where it came from.
+type Credential struct {
+	Value  string
+	Source string
+}
+
+// ExtractCredentials returns the credentials carried by r in precedence order: the Authorization
+// bearer token, the X-Goog-Api-Key and X-Api-Key headers, then the key and auth_token query
+// parameters. Empty values are skipped.
+func ExtractCredentials(r *http.Request) []Credential {
+	if r == nil {
+		return nil
+	}
+	queryKey := ""
+	queryAuthToken := ""
+	if r.URL != nil {
+		queryKey = r.URL.Query().Get("key")
+		queryAuthToken = r.URL.Query().Get("auth_token")
+	}
+	candidates := []Credential{
+		{extractBearerToken(r.Header.Get("Authorization")), "authorization"},
+		{r.Header.Get("X-Goog-Api-Key"), "x-goog-api-key"},
+		{r.Header.Get("X-Api-Key"), "x-api-key"},
+		{queryKey, "query-key"},
+		{queryAuthToken, "query-auth-token"},
+	}
+	out := candidates[:0]
+	for _, candidate := range candidates {
+		if candidate.Value != "" {
+			out = append(out, candidate)
+		}
+	}
+	return out
+}
+
+func extractBearerToken(header string) string {
+	if header == "" {
+		return ""
+	}
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 {
+		return header
+	}
+	if strings.ToLower(parts[0]) != "bearer" {
+		return header
+	}
+	return strings.TrimSpace(parts[1])
+}
@@ -22,6 +22,21 @@ type Result struct {
 	Metadata  map[string]string
 }
 
+// Result metadata keys recognised by the API handlers. Values are comma-separated lists and restrict
+// the authenticated principal the same way the matching client-keys fields do.
+const (
+	MetadataAllowedModels    = "allowed-models"
+	MetadataDeniedModels     = "denied-models"
+	MetadataAllowedProviders = "allowed-providers"
+)
+
+// MetadataTenant is the Result metadata key naming the tenant the principal belongs to.
+const MetadataTenant = "tenant"
+
+// MetadataScopes is the Result metadata key listing, comma-separated, the API families the principal
+// may call. Absent means every family.
+const MetadataScopes = "scopes"
+
 // ProviderFactory builds a provider from configuration data.
 type ProviderFactory func(cfg *config.AccessProvider, root *config.SDKConfig) (Provider, error)
 
@@ -74,8 +89,8 @@ func BuildProviders(root *config.SDKConfig) ([]Provider, error) {
 		}
 		providers = append(providers, provider)
 	}
-	if len(providers) == 0 {
-		if inline := config.MakeInlineAPIKeyProvider(root.APIKeys); inline != nil {
+	if !root.HasConfigAPIKeyProvider() {
+		if inline := root.InlineAccessProvider(); inline != nil {
 			provider, err := BuildProvider(inline, root)
 			if err != nil {
 				return nil, err
@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/interfaces"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
 	coreauth "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/auth"
 	coreexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
 	"github.com/router-for-me/CLIProxyAPI/v6/sdk/config"
@@ -138,7 +139,7 @@ func (h *BaseAPIHandler) GetContextWithCancel(handler interfaces.APIHandler, c *
 // ExecuteWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
-	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
+	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(ctx, modelName)
 	if errMsg != nil {
 		return nil, errMsg
 	}
@@ -158,7 +159,8 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 	if cloned := cloneMetadata(metadata); cloned != nil {
 		opts.Metadata = cloned
 	}
-	resp, err := h.AuthManager.Execute(ctx, providers, req, opts)
+	withConversationHint(ctx, &opts)
+	resp, err := h.AuthManager.Execute(h.withClientKeyPolicy(withServedModelHeader(ctx)), providers, req, opts)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
@@ -180,7 +182,7 @@ func (h *BaseAPIHandler) ExecuteWithAuthManager(ctx context.Context, handlerType
 // ExecuteCountWithAuthManager executes a non-streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) ([]byte, *interfaces.ErrorMessage) {
-	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
+	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(ctx, modelName)
 	if errMsg != nil {
 		return nil, errMsg
 	}
@@ -200,7 +202,7 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 	if cloned := cloneMetadata(metadata); cloned != nil {
 		opts.Metadata = cloned
 	}
-	resp, err := h.AuthManager.ExecuteCount(ctx, providers, req, opts)
+	resp, err := h.AuthManager.ExecuteCount(h.withClientKeyPolicy(ctx), providers, req, opts)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if se, ok := err.(interface{ StatusCode() int }); ok && se != nil {
@@ -222,7 +224,7 @@ func (h *BaseAPIHandler) ExecuteCountWithAuthManager(ctx context.Context, handle
 // ExecuteStreamWithAuthManager executes a streaming request via the core auth manager.
 // This path is the only supported execution route.
 func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handlerType, modelName string, rawJSON []byte, alt string) (<-chan []byte, <-chan *interfaces.ErrorMessage) {
-	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(modelName)
+	providers, normalizedModel, metadata, errMsg := h.getRequestDetails(ctx, modelName)
 	if errMsg != nil {
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		errChan <- errMsg
@@ -245,7 +247,8 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 	if cloned := cloneMetadata(metadata); cloned != nil {
 		opts.Metadata = cloned
 	}
-	chunks, err := h.AuthManager.ExecuteStream(ctx, providers, req, opts)
+	withConversationHint(ctx, &opts)
+	chunks, err := h.AuthManager.ExecuteStream(h.withClientKeyPolicy(withServedModelHeader(ctx)), providers, req, opts)
 	if err != nil {
 		errChan := make(chan *interfaces.ErrorMessage, 1)
 		status := http.StatusInternalServerError
@@ -269,6 +272,14 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 	go func() {
 		defer close(dataChan)
 		defer close(errChan)
+		// The handler stops reading when the client disconnects and cancels ctx; drain the rest of
+		// the stream then so the upstream relay and its concurrency slot are released.
+		defer func() {
+			go func() {
+				for range chunks {
+				}
+			}()
+		}()
 		for chunk := range chunks {
 			if chunk.Err != nil {
 				status := http.StatusInternalServerError
@@ -287,14 +298,18 @@ func (h *BaseAPIHandler) ExecuteStreamWithAuthManager(ctx context.Context, handl
 				return
 			}
 			if len(chunk.Payload) > 0 {
-				dataChan <- cloneBytes(chunk.Payload)
+				select {
+				case dataChan <- cloneBytes(chunk.Payload):
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}()
 	return dataChan, errChan
 }
 
-func (h *BaseAPIHandler) getRequestDetails(modelName string) (providers []string, normalizedModel string, metadata map[string]any, err *interfaces.ErrorMessage) {
+func (h *BaseAPIHandler) getRequestDetails(ctx context.Context, modelName string) (providers []string, normalizedModel string, metadata map[string]any, err *interfaces.ErrorMessage) {
 	providerName, extractedModelName, isDynamic := h.parseDynamicModel(modelName)
 
 	// First, normalize the model name to handle suffixes like "-thinking-128"
@@ -311,7 +326,7 @@ func (h *BaseAPIHandler) getRequestDetails(modelName string) (providers []string
 		providers = util.GetProviderName(normalizedModel)
 	}
 
-	if len(providers) == 0 {
+	if len(providers) == 0 && (h.AuthManager == nil || len(h.AuthManager.ModelFallbacks(normalizedModel)) == 0) {
 		return nil, "", nil, &interfaces.ErrorMessage{StatusCode: http.StatusBadRequest, Error: fmt.Errorf("unknown provider for model %s", modelName)}
 	}
 
@@ -319,9 +334,176 @@ func (h *BaseAPIHandler) getRequestDetails(modelName string) (providers []string
 	// If it's a non-dynamic model, normalizedModel was set by normalizeModelMetadata.
 	// So, normalizedModel is already correctly set at this point.
 
+	if policy := h.clientKeyPolicy(ctx); policy != nil {
+		if !policy.AllowsModel(normalizedModel) {
+			return nil, "", nil, &interfaces.ErrorMessage{StatusCode: http.StatusForbidden, Error: fmt.Errorf("model %s is not allowed for this API key", modelName)}
+		}
+		allowed := make([]string, 0, len(providers))
+		for _, provider := range providers {
+			if policy.AllowsProvider(provider) {
+				allowed = append(allowed, provider)
+			}
+		}
+		if len(providers) > 0 && len(allowed) == 0 {
+			return nil, "", nil, &interfaces.ErrorMessage{StatusCode: http.StatusForbidden, Error: fmt.Errorf("model %s is not allowed for this API key", modelName)}
+		}
+		providers = allowed
+	}
+
 	return providers, normalizedModel, metadata, nil
 }
 
+// clientKeyPolicy returns the client-keys entry of the API key that authenticated the request, if any.
+func (h *BaseAPIHandler) clientKeyPolicy(ctx context.Context) *config.ClientKey {
+	if h.Cfg == nil {
+		return nil
+	}
+	c, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || c == nil {
+		return nil
+	}
+	return clientKeyPolicyFromGin(h.Cfg, c)
+}
+
+// clientKeyPolicyFromGin resolves the access policy of the request: the client-keys entry of its
+// API key, or else the restrictions attached by the access provider that authenticated it.
+func clientKeyPolicyFromGin(cfg *config.SDKConfig, c *gin.Context) *config.ClientKey {
+	if policy := cfg.LookupClientKey(c.GetString("clientKey")); policy != nil {
+		return policy
+	}
+	raw, ok := c.Get("accessMetadata")
+	if !ok {
+		return nil
+	}
+	metadata, _ := raw.(map[string]string)
+	allowedModels := splitPolicyList(metadata[sdkaccess.MetadataAllowedModels])
+	deniedModels := splitPolicyList(metadata[sdkaccess.MetadataDeniedModels])
+	allowedProviders := splitPolicyList(metadata[sdkaccess.MetadataAllowedProviders])
+	if len(allowedModels) == 0 && len(deniedModels) == 0 && len(allowedProviders) == 0 {
+		return nil
+	}
+	return &config.ClientKey{
+		AllowedModels:    allowedModels,
+		DeniedModels:     deniedModels,
+		AllowedProviders: allowedProviders,
+	}
+}
+
+func splitPolicyList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// credentialGroupFromGin returns the credential group the request is restricted to: the group of its
+// client-keys entry, or else the tenant reported by the access provider that authenticated it.
+func credentialGroupFromGin(cfg *config.SDKConfig, c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	if entry := cfg.LookupClientKey(c.GetString("clientKey")); entry != nil && strings.TrimSpace(entry.Group) != "" {
+		return strings.TrimSpace(entry.Group)
+	}
+	if raw, ok := c.Get("accessMetadata"); ok {
+		metadata, _ := raw.(map[string]string)
+		return strings.TrimSpace(metadata[sdkaccess.MetadataTenant])
+	}
+	return ""
+}
+
+// withClientKeyPolicy carries the client key policy and credential group into the auth manager so
+// credential selection and model fallbacks honour them.
+func (h *BaseAPIHandler) withClientKeyPolicy(ctx context.Context) context.Context {
+	if c, ok := ctx.Value("gin").(*gin.Context); ok && h.Cfg != nil {
+		ctx = coreauth.WithCredentialGroup(ctx, credentialGroupFromGin(h.Cfg, c))
+	}
+	policy := h.clientKeyPolicy(ctx)
+	if policy == nil {
+		return ctx
+	}
+	return coreauth.WithModelAccessFilter(ctx, func(provider, model string) bool {
+		return policy.AllowsModel(model) && policy.AllowsProvider(provider)
+	})
+}
+
+// FilterModels drops the models the API key of the request may not use from a model listing.
+// Entries are matched by their "id", or by their "name" without the "models/" prefix.
+func (h *BaseAPIHandler) FilterModels(c *gin.Context, models []map[string]any) []map[string]any {
+	if h.Cfg == nil || c == nil {
+		return models
+	}
+	policy := clientKeyPolicyFromGin(h.Cfg, c)
+	if policy == nil {
+		return models
+	}
+	filtered := make([]map[string]any, 0, len(models))
+	for _, model := range models {
+		id, _ := model["id"].(string)
+		if id == "" {
+			name, _ := model["name"].(string)
+			id = strings.TrimPrefix(name, "models/")
+		}
+		if id != "" && h.ModelAllowed(c, id) {
+			filtered = append(filtered, model)
+		}
+	}
+	return filtered
+}
+
+// ModelAllowed reports whether the API key of the request may use model through any of its providers.
+func (h *BaseAPIHandler) ModelAllowed(c *gin.Context, model string) bool {
+	if h.Cfg == nil || c == nil {
+		return true
+	}
+	policy := clientKeyPolicyFromGin(h.Cfg, c)
+	if policy == nil {
+		return true
+	}
+	if !policy.AllowsModel(model) {
+		return false
+	}
+	providers := util.GetProviderName(model)
+	if len(providers) == 0 {
+		return len(policy.AllowedProviders) == 0
+	}
+	for _, provider := range providers {
+		if policy.AllowsProvider(provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// withConversationHint forwards an explicit client conversation identifier to credential selection.
+func withConversationHint(ctx context.Context, opts *coreexecutor.Options) {
+	c, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || c == nil || c.Request == nil {
+		return
+	}
+	if conversation := strings.TrimSpace(c.GetHeader(coreauth.ConversationHeader)); conversation != "" {
+		if opts.Metadata == nil {
+			opts.Metadata = make(map[string]any)
+		}
+		opts.Metadata[coreauth.ConversationKeyMetadataKey] = conversation
+	}
+}
+
+// withServedModelHeader reports the model that actually served the request, which differs from the
+// requested one when a model fallback kicked in, through a response header on the gin context.
+func withServedModelHeader(ctx context.Context) context.Context {
+	c, ok := ctx.Value("gin").(*gin.Context)
+	if !ok || c == nil {
+		return ctx
+	}
+	return coreauth.WithServedModelCallback(ctx, func(model string) {
+		c.Header(coreauth.ServedModelHeader, model)
+	})
+}
+
 func (h *BaseAPIHandler) parseDynamicModel(modelName string) (providerName, model string, isDynamic bool) {
 	var providerPart, modelPart string
 	for _, sep := range []string{"://"} {
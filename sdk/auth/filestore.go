@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -149,6 +150,48 @@ func (s *FileTokenStore) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// runtimeStateFileName is the auth directory sidecar holding runtime auth state. It has no .json
+// suffix so it is never mistaken for a credential file.
+const runtimeStateFileName = ".runtime-state"
+
+// LoadRuntimeState reads the runtime auth state sidecar from the auth directory.
+func (s *FileTokenStore) LoadRuntimeState(_ context.Context) ([]byte, error) {
+	dir := s.baseDirSnapshot()
+	if dir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, runtimeStateFileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("auth filestore: read runtime state failed: %w", err)
+	}
+	return data, nil
+}
+
+// SaveRuntimeState replaces the runtime auth state sidecar in the auth directory.
+func (s *FileTokenStore) SaveRuntimeState(_ context.Context, data []byte) error {
+	dir := s.baseDirSnapshot()
+	if dir == "" {
+		return fmt.Errorf("auth filestore: directory not configured")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("auth filestore: create dir failed: %w", err)
+	}
+	path := filepath.Join(dir, runtimeStateFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("auth filestore: write runtime state failed: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("auth filestore: rename runtime state failed: %w", err)
+	}
+	return nil
+}
+
 func (s *FileTokenStore) resolveDeletePath(id string) (string, error) {
 	if strings.ContainsRune(id, os.PathSeparator) || filepath.IsAbs(id) {
 		return id, nil
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// ConversationHeader lets clients pin a conversation explicitly.
+	ConversationHeader = "X-Conversation-Id"
+	// ConversationKeyMetadataKey carries the ConversationHeader value in Options.Metadata.
+	ConversationKeyMetadataKey = "conversation_key"
+
+	// DefaultAffinityTTL is how long an idle conversation stays bound to its credential.
+	DefaultAffinityTTL = 30 * time.Minute
+)
+
+type affinityBinding struct {
+	authID    string
+	expiresAt time.Time
+}
+
+// AffinitySelector keeps every turn of a conversation on the same credential so per-account
+// prompt caches are reused. New conversations are spread by rendezvous hashing over the available
+// credentials; when the bound credential is blocked the conversation moves on via round-robin.
+// Requests without a recognisable conversation key are plain round-robin.
+type AffinitySelector struct {
+	// TTL is how long a binding survives without traffic. Zero means DefaultAffinityTTL.
+	TTL time.Duration
+
+	fallback  RoundRobinSelector
+	mu        sync.Mutex
+	bindings  map[string]affinityBinding
+	lastSweep time.Time
+}
+
+// Pick selects the auth bound to the request's conversation, binding one when needed.
+func (s *AffinitySelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	conversation := conversationKey(opts)
+	if conversation == "" {
+		return s.fallback.Pick(ctx, provider, model, opts, auths)
+	}
+	available, err := availableAuths(provider, model, auths)
+	if err != nil {
+		return nil, err
+	}
+	key := provider + "|" + conversation
+	now := time.Now()
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = DefaultAffinityTTL
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bindings == nil {
+		s.bindings = make(map[string]affinityBinding)
+	}
+	if now.Sub(s.lastSweep) > ttl {
+		for k, binding := range s.bindings {
+			if now.After(binding.expiresAt) {
+				delete(s.bindings, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	var selected *Auth
+	if binding, ok := s.bindings[key]; ok && now.Before(binding.expiresAt) {
+		for _, candidate := range available {
+			if candidate.ID == binding.authID {
+				selected = candidate
+				break
+			}
+		}
+		if selected == nil {
+			// The bound credential is blocked or gone; move the conversation along.
+			selected, err = s.fallback.Pick(ctx, provider, model, opts, available)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		selected = rendezvousPick(conversation, available)
+	}
+	s.bindings[key] = affinityBinding{authID: selected.ID, expiresAt: now.Add(ttl)}
+	return selected, nil
+}
+
+// rendezvousPick returns the candidate with the highest hash for key, so the same conversation maps
+// to the same credential while the candidate set is stable.
+func rendezvousPick(key string, auths []*Auth) *Auth {
+	var best *Auth
+	var bestScore uint64
+	for _, candidate := range auths {
+		sum := sha256.Sum256([]byte(key + "\x00" + candidate.ID))
+		score := binary.BigEndian.Uint64(sum[:8])
+		if best == nil || score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best
+}
+
+// conversationKey derives a stable conversation identifier from the client request. It prefers an
+// explicit ConversationHeader, then Claude metadata.user_id and Codex prompt_cache_key, and finally
+// hashes the system prompt together with the first user message.
+func conversationKey(opts cliproxyexecutor.Options) string {
+	if v, ok := opts.Metadata[ConversationKeyMetadataKey].(string); ok && strings.TrimSpace(v) != "" {
+		return "header:" + strings.TrimSpace(v)
+	}
+	payload := opts.OriginalRequest
+	if len(payload) == 0 || !gjson.ValidBytes(payload) {
+		return ""
+	}
+	if v := strings.TrimSpace(gjson.GetBytes(payload, "metadata.user_id").String()); v != "" {
+		return "user:" + v
+	}
+	if v := strings.TrimSpace(gjson.GetBytes(payload, "prompt_cache_key").String()); v != "" {
+		return "cache:" + v
+	}
+
+	root := gjson.ParseBytes(payload)
+	if request := root.Get("request"); request.IsObject() {
+		// Gemini CLI envelopes wrap the generateContent body in "request".
+		root = request
+	}
+	system := firstExisting(root, "system", "instructions", "systemInstruction", "system_instruction")
+	var firstUser gjson.Result
+	for _, path := range []string{"messages", "contents", "input"} {
+		items := root.Get(path)
+		if !items.IsArray() {
+			continue
+		}
+		items.ForEach(func(_, item gjson.Result) bool {
+			switch item.Get("role").String() {
+			case "system", "developer":
+				if !system.Exists() {
+					system = item.Get("content")
+				}
+			case "user":
+				firstUser = item
+				return false
+			}
+			return true
+		})
+		break
+	}
+	if !firstUser.Exists() {
+		if input := root.Get("input"); input.Type == gjson.String {
+			firstUser = input
+		}
+	}
+	if !system.Exists() && !firstUser.Exists() {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(system.Raw + "\x00" + firstUser.Raw))
+	return "prompt:" + hex.EncodeToString(sum[:16])
+}
+
+func firstExisting(root gjson.Result, paths ...string) gjson.Result {
+	for _, path := range paths {
+		if v := root.Get(path); v.Exists() {
+			return v
+		}
+	}
+	return gjson.Result{}
+}
@@ -0,0 +1,351 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerMinRequests      = 20
+	defaultBreakerWindow           = time.Minute
+	defaultBreakerCooldown         = 30 * time.Second
+	defaultBreakerMaxCooldown      = 10 * time.Minute
+	// breakerProbeTimeout releases a half-open probe whose result never arrived.
+	breakerProbeTimeout = 5 * time.Minute
+)
+
+// BreakerStatus is the position of a circuit breaker.
+type BreakerStatus string
+
+const (
+	// BreakerClosed lets traffic through while failures are counted.
+	BreakerClosed BreakerStatus = "closed"
+	// BreakerOpen rejects traffic until the cooldown ends.
+	BreakerOpen BreakerStatus = "open"
+	// BreakerHalfOpen admits a single probe request whose outcome closes or re-opens the breaker.
+	BreakerHalfOpen BreakerStatus = "half-open"
+)
+
+// BreakerState tracks upstream failures for an auth or a single model of an auth.
+type BreakerState struct {
+	// State is the breaker position. Empty means closed.
+	State BreakerStatus `json:"state,omitempty"`
+	// ConsecutiveFailures counts failures since the last success.
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
+	// WindowRequests and WindowFailures count outcomes in the current error-rate window.
+	WindowRequests int `json:"window_requests,omitempty"`
+	WindowFailures int `json:"window_failures,omitempty"`
+	// WindowStart is when the current error-rate window began.
+	WindowStart time.Time `json:"window_start"`
+	// OpenedAt is when the breaker last opened.
+	OpenedAt time.Time `json:"opened_at"`
+	// NextProbeAt is when an open breaker admits its half-open probe.
+	NextProbeAt time.Time `json:"next_probe_at"`
+	// ProbeStartedAt is when the outstanding half-open probe was sent.
+	ProbeStartedAt time.Time `json:"probe_started_at"`
+	// Trips counts openings since the breaker last closed and drives the cooldown backoff.
+	Trips int `json:"trips,omitempty"`
+}
+
+// BreakerPolicy decides when a breaker opens and how long it stays open. Zero values use defaults.
+type BreakerPolicy struct {
+	// Disabled turns circuit breaking off and closes every breaker.
+	Disabled bool
+	// FailureThreshold opens the breaker after this many consecutive failures.
+	FailureThreshold int
+	// ErrorRate opens the breaker when the failure ratio within Window reaches it. Zero disables it.
+	ErrorRate float64
+	// MinRequests is the number of outcomes within Window required before ErrorRate applies.
+	MinRequests int
+	// Window is the length of the error-rate window.
+	Window time.Duration
+	// Cooldown is how long the breaker stays open before the first probe; failed probes double it.
+	Cooldown time.Duration
+	// MaxCooldown caps the doubled cooldown.
+	MaxCooldown time.Duration
+}
+
+// AuthBreakerStatus is a snapshot of the breakers of one auth.
+type AuthBreakerStatus struct {
+	AuthID   string       `json:"auth_id"`
+	Provider string       `json:"provider,omitempty"`
+	Label    string       `json:"label,omitempty"`
+	Breaker  BreakerState `json:"breaker"`
+	// Models holds the per-model breakers that have seen traffic.
+	Models map[string]BreakerState `json:"models,omitempty"`
+}
+
+func (p BreakerPolicy) withDefaults() BreakerPolicy {
+	if p.FailureThreshold <= 0 {
+		p.FailureThreshold = defaultBreakerFailureThreshold
+	}
+	if p.MinRequests <= 0 {
+		p.MinRequests = defaultBreakerMinRequests
+	}
+	if p.Window <= 0 {
+		p.Window = defaultBreakerWindow
+	}
+	if p.Cooldown <= 0 {
+		p.Cooldown = defaultBreakerCooldown
+	}
+	if p.MaxCooldown <= 0 {
+		p.MaxCooldown = defaultBreakerMaxCooldown
+	}
+	if p.MaxCooldown < p.Cooldown {
+		p.MaxCooldown = p.Cooldown
+	}
+	return p
+}
+
+// SetBreakerPolicy replaces the circuit breaker policy. Disabling it closes every breaker.
+func (m *Manager) SetBreakerPolicy(policy BreakerPolicy) {
+	m.mu.Lock()
+	m.breakerPolicy = policy
+	if policy.Disabled {
+		for _, auth := range m.auths {
+			auth.Breaker = BreakerState{}
+			for _, state := range auth.ModelStates {
+				if state != nil {
+					state.Breaker = BreakerState{}
+				}
+			}
+		}
+		m.markRuntimeStateDirty()
+	}
+	m.mu.Unlock()
+}
+
+// BreakerPolicy returns the active circuit breaker policy.
+func (m *Manager) BreakerPolicy() BreakerPolicy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.breakerPolicy
+}
+
+// BreakerStates returns the circuit breaker state of every registered auth, sorted by ID.
+func (m *Manager) BreakerStates() []AuthBreakerStatus {
+	m.mu.RLock()
+	out := make([]AuthBreakerStatus, 0, len(m.auths))
+	for _, a := range m.auths {
+		status := AuthBreakerStatus{AuthID: a.ID, Provider: a.Provider, Label: a.Label, Breaker: a.Breaker}
+		for model, state := range a.ModelStates {
+			if state == nil || state.Breaker.State == "" {
+				continue
+			}
+			if status.Models == nil {
+				status.Models = make(map[string]BreakerState)
+			}
+			status.Models[model] = state.Breaker
+		}
+		out = append(out, status)
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].AuthID < out[j].AuthID })
+	return out
+}
+
+// breakerOutcome classifies a result for the breakers. Only transport errors, timeouts and 5xx
+// responses count as failures; quota and auth errors already have their own cooldowns, and
+// requests abandoned by the client say nothing about the upstream.
+func breakerOutcome(ctx context.Context, result Result) (counted, failed bool) {
+	if result.Success {
+		return true, false
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return false, false
+	}
+	status := statusCodeFromResult(result.Error)
+	if status == 0 || status == http.StatusRequestTimeout || status >= http.StatusInternalServerError {
+		return true, true
+	}
+	return false, false
+}
+
+// recordBreakers folds a result into the auth and auth+model breakers. Callers hold m.mu.
+func (m *Manager) recordBreakers(ctx context.Context, auth *Auth, result Result, now time.Time) {
+	policy := m.breakerPolicy
+	if policy.Disabled {
+		return
+	}
+	policy = policy.withDefaults()
+	counted, failed := breakerOutcome(ctx, result)
+	breakers := []*BreakerState{&auth.Breaker}
+	names := []string{""}
+	if result.Model != "" {
+		if state := ensureModelState(auth, result.Model); state != nil {
+			breakers = append(breakers, &state.Breaker)
+			names = append(names, result.Model)
+		}
+	}
+	for i, breaker := range breakers {
+		before := breaker.State
+		if !counted {
+			breaker.release(now)
+			continue
+		}
+		breaker.record(failed, policy, now)
+		if breaker.State == before {
+			continue
+		}
+		scope := auth.ID
+		if names[i] != "" {
+			scope += " model " + names[i]
+		}
+		switch breaker.State {
+		case BreakerOpen:
+			log.Warnf("circuit breaker opened for auth %s until %s", scope, breaker.NextProbeAt.Format(time.RFC3339))
+		case BreakerClosed:
+			if before != "" {
+				log.Infof("circuit breaker closed for auth %s", scope)
+			}
+		}
+	}
+}
+
+// record applies one counted outcome.
+func (b *BreakerState) record(failed bool, policy BreakerPolicy, now time.Time) {
+	switch b.State {
+	case BreakerOpen, BreakerHalfOpen:
+		if !failed {
+			b.close(now)
+		} else if b.State == BreakerHalfOpen {
+			b.open(policy, now)
+		}
+		// A failure from a request started before the breaker opened changes nothing.
+		return
+	}
+	b.State = BreakerClosed
+	if b.WindowStart.IsZero() || now.Sub(b.WindowStart) > policy.Window {
+		b.WindowStart = now
+		b.WindowRequests = 0
+		b.WindowFailures = 0
+	}
+	b.WindowRequests++
+	if !failed {
+		b.ConsecutiveFailures = 0
+		return
+	}
+	b.ConsecutiveFailures++
+	b.WindowFailures++
+	overRate := policy.ErrorRate > 0 && b.WindowRequests >= policy.MinRequests &&
+		float64(b.WindowFailures)/float64(b.WindowRequests) >= policy.ErrorRate
+	if b.ConsecutiveFailures >= policy.FailureThreshold || overRate {
+		b.open(policy, now)
+	}
+}
+
+func (b *BreakerState) open(policy BreakerPolicy, now time.Time) {
+	cooldown := policy.Cooldown
+	for i := 0; i < b.Trips && cooldown < policy.MaxCooldown; i++ {
+		cooldown *= 2
+	}
+	if cooldown > policy.MaxCooldown {
+		cooldown = policy.MaxCooldown
+	}
+	b.State = BreakerOpen
+	b.Trips++
+	b.OpenedAt = now
+	b.NextProbeAt = now.Add(cooldown)
+	b.ProbeStartedAt = time.Time{}
+	b.WindowStart = time.Time{}
+	b.WindowRequests = 0
+	b.WindowFailures = 0
+}
+
+func (b *BreakerState) close(now time.Time) {
+	*b = BreakerState{State: BreakerClosed, WindowStart: now, WindowRequests: 1}
+}
+
+// release hands an inconclusive half-open probe back so the next request probes instead.
+func (b *BreakerState) release(now time.Time) {
+	if b.State == BreakerHalfOpen {
+		b.State = BreakerOpen
+		b.NextProbeAt = now
+		b.ProbeStartedAt = time.Time{}
+	}
+}
+
+// blocked reports whether the breaker rejects traffic at now, and until when.
+func (b *BreakerState) blocked(now time.Time) (bool, time.Time) {
+	switch b.State {
+	case BreakerOpen:
+		if now.Before(b.NextProbeAt) {
+			return true, b.NextProbeAt
+		}
+	case BreakerHalfOpen:
+		if until := b.ProbeStartedAt.Add(breakerProbeTimeout); now.Before(until) {
+			return true, until
+		}
+	}
+	return false, time.Time{}
+}
+
+// probeDue reports whether the next request through the breaker is its half-open probe.
+func (b *BreakerState) probeDue(now time.Time) bool {
+	if b.State != BreakerOpen && b.State != BreakerHalfOpen {
+		return false
+	}
+	blocked, _ := b.blocked(now)
+	return !blocked
+}
+
+// breakerBlocked reports whether the auth or its model breaker rejects traffic at now.
+func breakerBlocked(auth *Auth, model string, now time.Time) (bool, time.Time) {
+	blocked, next := auth.Breaker.blocked(now)
+	if model != "" {
+		if state, ok := auth.ModelStates[model]; ok && state != nil {
+			if modelBlocked, modelNext := state.Breaker.blocked(now); modelBlocked {
+				if !blocked || modelNext.After(next) {
+					next = modelNext
+				}
+				blocked = true
+			}
+		}
+	}
+	return blocked, next
+}
+
+func breakerProbeDue(auth *Auth, model string, now time.Time) bool {
+	if auth.Breaker.probeDue(now) {
+		return true
+	}
+	if model != "" {
+		if state, ok := auth.ModelStates[model]; ok && state != nil {
+			return state.Breaker.probeDue(now)
+		}
+	}
+	return false
+}
+
+// claimBreakerProbe turns the due breakers of the selected auth half-open. It fails when another
+// request claimed the probe first.
+func (m *Manager) claimBreakerProbe(authID, model string) bool {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	auth, ok := m.auths[authID]
+	if !ok || auth == nil {
+		return false
+	}
+	if blocked, _ := breakerBlocked(auth, model, now); blocked {
+		return false
+	}
+	breakers := []*BreakerState{&auth.Breaker}
+	if model != "" {
+		if state, okState := auth.ModelStates[model]; okState && state != nil {
+			breakers = append(breakers, &state.Breaker)
+		}
+	}
+	for _, breaker := range breakers {
+		if breaker.probeDue(now) {
+			breaker.State = BreakerHalfOpen
+			breaker.ProbeStartedAt = now
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AttributeMaxConcurrency caps the number of simultaneous upstream requests on a credential.
+// Missing or non-positive values mean unlimited.
+const AttributeMaxConcurrency = "max_concurrency"
+
+// DefaultConcurrencyWait is how long a request queues when every credential is at its max
+// concurrency or out of rate limit budget.
+const DefaultConcurrencyWait = 30 * time.Second
+
+// errSlotTaken signals that the selected credential filled up between the check and the reservation.
+var errSlotTaken = errors.New("concurrency slot taken")
+
+// SetConcurrencyWait sets how long requests queue for a concurrency slot. Zero restores the
+// default; a negative value fails saturated requests immediately.
+func (m *Manager) SetConcurrencyWait(wait time.Duration) {
+	m.mu.Lock()
+	m.concurrencyWait = wait
+	m.mu.Unlock()
+}
+
+// ConcurrencyWait returns the effective queueing timeout for saturated credentials.
+func (m *Manager) ConcurrencyWait() time.Duration {
+	m.mu.RLock()
+	wait := m.concurrencyWait
+	m.mu.RUnlock()
+	if wait == 0 {
+		return DefaultConcurrencyWait
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+func authMaxConcurrency(a *Auth) int {
+	if a == nil || a.Attributes == nil {
+		return 0
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(a.Attributes[AttributeMaxConcurrency]))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// pickWait describes why a selection attempt could not reserve a credential yet.
+type pickWait struct {
+	// queued is set when usable credentials exist but are at max concurrency or out of rate budget.
+	queued bool
+	// retryIn is the shortest time until a rate-limited credential has budget again.
+	retryIn time.Duration
+	// rateLimitedOnly is set when no credential is merely waiting on a concurrency slot.
+	rateLimitedOnly bool
+}
+
+// waitForSlot blocks until a request completes, a rate-limited credential regains budget, the
+// deadline passes or ctx ends. A rate limit that cannot recover before the deadline fails at once.
+func waitForSlot(ctx context.Context, released <-chan struct{}, deadline time.Time, wait pickWait) error {
+	remaining := time.Until(deadline)
+	if wait.rateLimitedOnly && wait.retryIn > remaining {
+		return &Error{
+			Code:       "rate_limited",
+			Message:    fmt.Sprintf("all credentials are over their rate limits, retry in %s", wait.retryIn.Round(time.Second)),
+			Retryable:  true,
+			HTTPStatus: http.StatusTooManyRequests,
+		}
+	}
+	if remaining <= 0 {
+		return &Error{Code: "auth_saturated", Message: "all credentials are at max concurrency", Retryable: true, HTTPStatus: http.StatusTooManyRequests}
+	}
+	sleep := remaining
+	if wait.retryIn > 0 && wait.retryIn < sleep {
+		sleep = wait.retryIn
+	}
+	timer := time.NewTimer(sleep)
+	defer timer.Stop()
+	select {
+	case <-released:
+		return nil
+	case <-timer.C:
+		// Either a rate limit recovered or the deadline passed; the next attempt decides.
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/util"
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ServedModelHeader is the response header carrying the model that actually served a request.
+const ServedModelHeader = "X-Served-Model"
+
+type servedModelContextKey struct{}
+
+// WithServedModelCallback returns a context that notifies fn with the model that served the request.
+// The callback fires before a non-streaming response is returned or a stream is handed back,
+// so HTTP handlers can still set response headers from it.
+func WithServedModelCallback(ctx context.Context, fn func(model string)) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, servedModelContextKey{}, fn)
+}
+
+func notifyServedModel(ctx context.Context, model string) {
+	if ctx == nil || model == "" {
+		return
+	}
+	if fn, ok := ctx.Value(servedModelContextKey{}).(func(string)); ok && fn != nil {
+		fn(model)
+	}
+}
+
+type modelAccessContextKey struct{}
+
+// WithModelAccessFilter returns a context that restricts model fallbacks to the provider and model
+// pairs accepted by allow, so a fallback chain cannot route a client where it may not go directly.
+func WithModelAccessFilter(ctx context.Context, allow func(provider, model string) bool) context.Context {
+	if allow == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, modelAccessContextKey{}, allow)
+}
+
+// allowedFallbackProviders drops the providers the request context may not use for model.
+func allowedFallbackProviders(ctx context.Context, providers []string, model string) []string {
+	allow, ok := ctx.Value(modelAccessContextKey{}).(func(string, string) bool)
+	if !ok || allow == nil {
+		return providers
+	}
+	out := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		if allow(provider, model) {
+			out = append(out, provider)
+		}
+	}
+	return out
+}
+
+// SetModelFallbacks replaces the fallback chains keyed by requested model.
+// Each chain is walked in order when no credential can serve the requested model.
+func (m *Manager) SetModelFallbacks(chains map[string][]string) {
+	var cloned map[string][]string
+	for model, fallbacks := range chains {
+		key := strings.TrimSpace(model)
+		if key == "" {
+			continue
+		}
+		list := make([]string, 0, len(fallbacks))
+		seen := map[string]struct{}{key: {}}
+		for _, fallback := range fallbacks {
+			name := strings.TrimSpace(fallback)
+			if name == "" {
+				continue
+			}
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			list = append(list, name)
+		}
+		if len(list) == 0 {
+			continue
+		}
+		if cloned == nil {
+			cloned = make(map[string][]string)
+		}
+		cloned[key] = list
+	}
+	m.mu.Lock()
+	m.modelFallbacks = cloned
+	m.mu.Unlock()
+}
+
+// ModelFallbacks returns a copy of the fallback chain configured for model.
+func (m *Manager) ModelFallbacks(model string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.modelFallbacks[model]...)
+}
+
+// shouldFallback reports whether err means the requested model could not be served by any
+// credential, as opposed to a client error that another model would reject just the same.
+func shouldFallback(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var cooldown *modelCooldownError
+	if errors.As(err, &cooldown) {
+		return true
+	}
+	var authErr *Error
+	if errors.As(err, &authErr) && authErr != nil {
+		switch authErr.Code {
+		case "auth_not_found", "auth_unavailable", "executor_not_found", "provider_not_found":
+			return true
+		}
+	}
+	switch status := statusCodeFromError(err); {
+	case status == http.StatusUnauthorized, status == http.StatusPaymentRequired, status == http.StatusForbidden:
+		return true
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests:
+		return true
+	case status >= http.StatusInternalServerError:
+		return true
+	}
+	return false
+}
+
+// fallbackRequest rewrites the request and options for a fallback model. The payload stays in the
+// client's source format; executors translate it to their own format through sdktranslator.
+func fallbackRequest(req cliproxyexecutor.Request, opts cliproxyexecutor.Options, model string) (cliproxyexecutor.Request, cliproxyexecutor.Options) {
+	req.Model = model
+	req.Payload = rewritePayloadModel(req.Payload, model)
+	req.Metadata = withoutModelHints(req.Metadata)
+	opts.OriginalRequest = rewritePayloadModel(opts.OriginalRequest, model)
+	opts.Metadata = withoutModelHints(opts.Metadata)
+	return req, opts
+}
+
+// withoutModelHints drops the thinking hints parsed from the primary model name, which do not
+// apply to a different model, while keeping request-level hints.
+func withoutModelHints(metadata map[string]any) map[string]any {
+	if len(metadata) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(metadata))
+	for key, value := range metadata {
+		switch key {
+		case util.GeminiThinkingBudgetMetadataKey, util.GeminiIncludeThoughtsMetadataKey, util.GeminiOriginalModelMetadataKey:
+			continue
+		}
+		out[key] = value
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func rewritePayloadModel(payload []byte, model string) []byte {
+	if len(payload) == 0 || !gjson.GetBytes(payload, "model").Exists() {
+		return payload
+	}
+	updated, err := sjson.SetBytes(payload, "model", model)
+	if err != nil {
+		return payload
+	}
+	return updated
+}
+
+// walkFallbacks tries each fallback model of primary in order until one succeeds. The primary error
+// is preserved when the whole chain fails so clients see the original cause.
+func walkFallbacks[T any](ctx context.Context, m *Manager, primary string, primaryErr error, run func(providers []string, model string) (T, error)) (T, bool) {
+	var zero T
+	if !shouldFallback(primaryErr) {
+		return zero, false
+	}
+	for _, fallback := range m.ModelFallbacks(primary) {
+		if ctx.Err() != nil {
+			return zero, false
+		}
+		providers := allowedFallbackProviders(ctx, util.GetProviderName(fallback), fallback)
+		if len(providers) == 0 {
+			log.Debugf("skipping fallback model %s for %s: no provider available", fallback, primary)
+			continue
+		}
+		log.Debugf("falling back from model %s to %s: %v", primary, fallback, primaryErr)
+		result, err := run(providers, fallback)
+		if err == nil {
+			notifyServedModel(ctx, fallback)
+			return result, true
+		}
+		if !shouldFallback(err) {
+			log.Debugf("fallback model %s failed: %v", fallback, err)
+			return zero, false
+		}
+	}
+	return zero, false
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// AttributeGroup assigns a credential to one or more comma-separated credential groups. Requests
+// carrying a group are only served by credentials of that group; requests without one only by
+// credentials without a group. Auths registered through the SDK set it in Attributes directly.
+const AttributeGroup = "group"
+
+type credentialGroupContextKey struct{}
+
+// WithCredentialGroup returns a context whose requests may only use credentials of group.
+// An empty group restricts the request to ungrouped credentials, which is also the default.
+func WithCredentialGroup(ctx context.Context, group string) context.Context {
+	group = strings.TrimSpace(group)
+	if group == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, credentialGroupContextKey{}, group)
+}
+
+// CredentialGroupFromContext returns the credential group requests of ctx are restricted to.
+func CredentialGroupFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	group, _ := ctx.Value(credentialGroupContextKey{}).(string)
+	return group
+}
+
+// authInGroup reports whether a may serve requests restricted to group.
+func authInGroup(a *Auth, group string) bool {
+	var raw string
+	if a != nil && a.Attributes != nil {
+		raw = strings.TrimSpace(a.Attributes[AttributeGroup])
+	}
+	if raw == "" || group == "" {
+		return raw == group
+	}
+	for _, item := range strings.Split(raw, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), group) {
+			return true
+		}
+	}
+	return false
+}
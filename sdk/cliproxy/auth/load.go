@@ -0,0 +1,260 @@
+package auth
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	// loadEWMAAlpha weights the newest latency sample in the moving averages.
+	loadEWMAAlpha = 0.3
+	// loadStatsStaleAfter marks latency averages as unknown again so idle credentials get re-probed.
+	loadStatsStaleAfter = 5 * time.Minute
+	// loadFailurePenalty is the latency sample recorded for a failed request, so credentials that
+	// keep failing rank behind working ones instead of looking unmeasured.
+	loadFailurePenalty = 30 * time.Second
+	// loadProbeBudget caps the concurrent requests sent to a credential without recent samples.
+	loadProbeBudget = 1
+)
+
+// AuthLoadStats is a snapshot of the runtime load and latency observed for one auth.
+type AuthLoadStats struct {
+	AuthID   string `json:"auth_id"`
+	Provider string `json:"provider,omitempty"`
+	Label    string `json:"label,omitempty"`
+	// InFlight is the number of upstream requests currently running on the auth.
+	InFlight int64 `json:"in_flight"`
+	// TTFBMillis is the moving average time to the first response byte in milliseconds.
+	TTFBMillis float64 `json:"ttfb_ewma_ms"`
+	// LatencyMillis is the moving average total request duration in milliseconds.
+	LatencyMillis float64 `json:"latency_ewma_ms"`
+	// Samples counts the requests folded into the averages; failures count with a penalty latency.
+	Samples int64 `json:"samples"`
+	// LastSampleAt is when the averages were last updated.
+	LastSampleAt time.Time `json:"last_sample_at,omitempty"`
+}
+
+type loadEntry struct {
+	inFlight     int64
+	ttfbMillis   float64
+	totalMillis  float64
+	samples      int64
+	lastSampleAt time.Time
+}
+
+// loadTracker keeps per-auth in-flight counters and latency moving averages.
+type loadTracker struct {
+	mu      sync.Mutex
+	entries map[string]*loadEntry
+	// released is closed and replaced whenever a request finishes, waking queued requests.
+	released chan struct{}
+}
+
+func newLoadTracker() *loadTracker {
+	return &loadTracker{entries: make(map[string]*loadEntry), released: make(chan struct{})}
+}
+
+func (t *loadTracker) entryLocked(authID string) *loadEntry {
+	entry, ok := t.entries[authID]
+	if !ok {
+		entry = &loadEntry{}
+		t.entries[authID] = entry
+	}
+	return entry
+}
+
+// tryBegin records an upstream request starting on the auth unless limit (when positive) requests
+// are already running on it.
+func (t *loadTracker) tryBegin(authID string, limit int) bool {
+	if t == nil || authID == "" {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry := t.entryLocked(authID)
+	if limit > 0 && entry.inFlight >= int64(limit) {
+		return false
+	}
+	entry.inFlight++
+	return true
+}
+
+// end records an upstream request on the auth finishing, successfully or not.
+func (t *loadTracker) end(authID string) {
+	if t == nil || authID == "" {
+		return
+	}
+	t.mu.Lock()
+	if entry, ok := t.entries[authID]; ok && entry.inFlight > 0 {
+		entry.inFlight--
+	}
+	close(t.released)
+	t.released = make(chan struct{})
+	t.mu.Unlock()
+}
+
+// saturated reports whether the auth already runs limit (when positive) requests.
+func (t *loadTracker) saturated(authID string, limit int) bool {
+	if t == nil || limit <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.entries[authID]
+	return ok && entry.inFlight >= int64(limit)
+}
+
+// releaseSignal returns a channel closed by the next request completion.
+func (t *loadTracker) releaseSignal() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.released
+}
+
+// observe folds a request's time to first byte and total duration into the averages.
+func (t *loadTracker) observe(authID string, ttfb, total time.Duration, now time.Time) {
+	if t == nil || authID == "" || total <= 0 {
+		return
+	}
+	if ttfb <= 0 || ttfb > total {
+		ttfb = total
+	}
+	ttfbMillis := float64(ttfb) / float64(time.Millisecond)
+	totalMillis := float64(total) / float64(time.Millisecond)
+	t.mu.Lock()
+	entry := t.entryLocked(authID)
+	if entry.samples == 0 || now.Sub(entry.lastSampleAt) > loadStatsStaleAfter {
+		entry.ttfbMillis = ttfbMillis
+		entry.totalMillis = totalMillis
+	} else {
+		entry.ttfbMillis += loadEWMAAlpha * (ttfbMillis - entry.ttfbMillis)
+		entry.totalMillis += loadEWMAAlpha * (totalMillis - entry.totalMillis)
+	}
+	entry.samples++
+	entry.lastSampleAt = now
+	t.mu.Unlock()
+}
+
+// observeFailure folds a failed request into the averages as a sample of at least
+// loadFailurePenalty.
+func (t *loadTracker) observeFailure(authID string, elapsed time.Duration, now time.Time) {
+	penalty := max(elapsed, loadFailurePenalty)
+	t.observe(authID, penalty, penalty, now)
+}
+
+// estimate returns the expected latency in milliseconds, the current in-flight count for the auth
+// and whether the latency is known; it is unknown without samples or when they are stale.
+func (t *loadTracker) estimate(authID string, stream bool, now time.Time) (float64, int64, bool) {
+	if t == nil {
+		return 0, 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.entries[authID]
+	if !ok {
+		return 0, 0, false
+	}
+	if entry.samples == 0 || now.Sub(entry.lastSampleAt) > loadStatsStaleAfter {
+		return 0, entry.inFlight, false
+	}
+	if stream {
+		return entry.ttfbMillis, entry.inFlight, true
+	}
+	return entry.totalMillis, entry.inFlight, true
+}
+
+func (t *loadTracker) stats(authID string) AuthLoadStats {
+	out := AuthLoadStats{AuthID: authID}
+	if t == nil {
+		return out
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if entry, ok := t.entries[authID]; ok {
+		out.InFlight = entry.inFlight
+		out.TTFBMillis = entry.ttfbMillis
+		out.LatencyMillis = entry.totalMillis
+		out.Samples = entry.samples
+		out.LastSampleAt = entry.lastSampleAt
+	}
+	return out
+}
+
+// failureCountsAgainstLatency reports whether a failed request reflects on the credential rather
+// than on the request itself, such as a malformed body or an unknown model.
+func failureCountsAgainstLatency(err *Error) bool {
+	if err == nil || err.HTTPStatus == 0 {
+		return true
+	}
+	switch err.HTTPStatus {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return err.HTTPStatus >= http.StatusInternalServerError
+}
+
+// LoadStats returns the in-flight and latency statistics for every registered auth, sorted by ID.
+func (m *Manager) LoadStats() []AuthLoadStats {
+	m.mu.RLock()
+	out := make([]AuthLoadStats, 0, len(m.auths))
+	for _, a := range m.auths {
+		stats := m.load.stats(a.ID)
+		stats.Provider = a.Provider
+		stats.Label = a.Label
+		out = append(out, stats)
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].AuthID < out[j].AuthID })
+	return out
+}
+
+// loadAwareSelector is implemented by selectors that need the manager's load statistics.
+type loadAwareSelector interface {
+	setLoadTracker(tracker *loadTracker)
+}
+
+// LatencySelector prefers the credential with the lowest expected latency scaled by its current
+// in-flight load. Streaming requests are ranked by time to first byte, others by total duration.
+// Credentials without recent samples rank first so they are probed, but only loadProbeBudget
+// requests at a time; beyond that they rank last until a sample arrives.
+type LatencySelector struct {
+	load *loadTracker
+}
+
+func (s *LatencySelector) setLoadTracker(tracker *loadTracker) { s.load = tracker }
+
+// Pick selects the least-loaded, fastest available auth.
+func (s *LatencySelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	available, err := availableAuths(provider, model, auths)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var best *Auth
+	var bestScore float64
+	var bestInFlight int64
+	for _, candidate := range available {
+		latency, inFlight, known := s.load.estimate(candidate.ID, opts.Stream, now)
+		var score float64
+		switch {
+		case known:
+			score = latency * float64(inFlight+1)
+		case inFlight < loadProbeBudget:
+			score = 0
+		default:
+			score = math.Inf(1)
+		}
+		if best == nil || score < bestScore ||
+			(score == bestScore && (inFlight < bestInFlight || (inFlight == bestInFlight && candidate.ID < best.ID))) {
+			best, bestScore, bestInFlight = candidate, score, inFlight
+		}
+	}
+	return best, nil
+}
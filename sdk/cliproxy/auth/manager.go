@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
@@ -64,6 +65,10 @@ type Result struct {
 	Success bool
 	// Error describes the failure when Success is false.
 	Error *Error
+	// Latency is the total upstream duration of the attempt, when measured.
+	Latency time.Duration
+	// FirstByte is the time until the first response payload, when measured.
+	FirstByte time.Duration
 }
 
 // Selector chooses an auth candidate for execution.
@@ -93,6 +98,45 @@ func (NoopHook) OnAuthUpdated(context.Context, *Auth) {}
 // OnResult implements Hook.
 func (NoopHook) OnResult(context.Context, Result) {}
 
+// RefreshHook is implemented by hooks that also observe credential refresh attempts.
+type RefreshHook interface {
+	// OnRefresh fires after a refresh attempt; err is nil when it succeeded.
+	OnRefresh(ctx context.Context, auth *Auth, err error)
+}
+
+// hookChain forwards callbacks to several hooks in registration order.
+type hookChain []Hook
+
+// OnAuthRegistered implements Hook.
+func (c hookChain) OnAuthRegistered(ctx context.Context, auth *Auth) {
+	for _, hook := range c {
+		hook.OnAuthRegistered(ctx, auth)
+	}
+}
+
+// OnAuthUpdated implements Hook.
+func (c hookChain) OnAuthUpdated(ctx context.Context, auth *Auth) {
+	for _, hook := range c {
+		hook.OnAuthUpdated(ctx, auth)
+	}
+}
+
+// OnResult implements Hook.
+func (c hookChain) OnResult(ctx context.Context, result Result) {
+	for _, hook := range c {
+		hook.OnResult(ctx, result)
+	}
+}
+
+// OnRefresh implements RefreshHook for the chained hooks that observe refreshes.
+func (c hookChain) OnRefresh(ctx context.Context, auth *Auth, err error) {
+	for _, hook := range c {
+		if refreshHook, ok := hook.(RefreshHook); ok {
+			refreshHook.OnRefresh(ctx, auth, err)
+		}
+	}
+}
+
 // Manager orchestrates auth lifecycle, selection, execution, and persistence.
 type Manager struct {
 	store     Store
@@ -107,6 +151,30 @@ type Manager struct {
 	// Optional HTTP RoundTripper provider injected by host.
 	rtProvider RoundTripperProvider
 
+	// retryPolicy controls in-place retries before failing over to another auth.
+	retryPolicy RetryPolicy
+	// modelFallbacks maps a requested model to the ordered models tried when it cannot be served.
+	modelFallbacks map[string][]string
+	// load tracks per-auth in-flight requests and latency averages.
+	load *loadTracker
+	// concurrencyWait bounds how long a request queues for a saturated or rate-limited credential.
+	concurrencyWait time.Duration
+	// limits enforces per-credential RPM/TPM budgets before dispatch.
+	limits *rateLimiter
+	// tokenEstimator approximates request input tokens for TPM budgets.
+	tokenEstimator TokenEstimator
+	// breakerPolicy controls the per-auth and per-auth+model circuit breakers.
+	breakerPolicy BreakerPolicy
+
+	// restoredState holds persisted runtime state not yet applied to a registered auth.
+	restoredState map[string]*runtimeState
+	// stateDirty marks runtime state changes not yet written to the store.
+	stateDirty     atomic.Bool
+	stateMu        sync.Mutex
+	stateFlushedAt time.Time
+	// stateSavedAuths is the serialized auth state last written, to skip unchanged writes.
+	stateSavedAuths []byte
+
 	// Auto refresh state
 	refreshCancel context.CancelFunc
 }
@@ -119,6 +187,10 @@ func NewManager(store Store, selector Selector, hook Hook) *Manager {
 	if hook == nil {
 		hook = NoopHook{}
 	}
+	load := newLoadTracker()
+	if aware, ok := selector.(loadAwareSelector); ok {
+		aware.setLoadTracker(load)
+	}
 	return &Manager{
 		store:           store,
 		executors:       make(map[string]ProviderExecutor),
@@ -126,6 +198,24 @@ func NewManager(store Store, selector Selector, hook Hook) *Manager {
 		hook:            hook,
 		auths:           make(map[string]*Auth),
 		providerOffsets: make(map[string]int),
+		load:            load,
+		limits:          newRateLimiter(),
+	}
+}
+
+// AddHook attaches hook after the hooks already installed. It must be called before the manager
+// registers auths or executes requests.
+func (m *Manager) AddHook(hook Hook) {
+	if m == nil || hook == nil {
+		return
+	}
+	switch existing := m.hook.(type) {
+	case nil, NoopHook:
+		m.hook = hook
+	case hookChain:
+		m.hook = append(existing, hook)
+	default:
+		m.hook = hookChain{existing, hook}
 	}
 }
 
@@ -172,8 +262,11 @@ func (m *Manager) Register(ctx context.Context, auth *Auth) (*Auth, error) {
 	if auth.ID == "" {
 		auth.ID = uuid.NewString()
 	}
+	stored := auth.Clone()
+	syncSchedulingAttributes(stored)
 	m.mu.Lock()
-	m.auths[auth.ID] = auth.Clone()
+	m.applyRestoredStateLocked(stored, true)
+	m.auths[auth.ID] = stored
 	m.mu.Unlock()
 	_ = m.persist(ctx, auth)
 	m.hook.OnAuthRegistered(ctx, auth.Clone())
@@ -185,15 +278,19 @@ func (m *Manager) Update(ctx context.Context, auth *Auth) (*Auth, error) {
 	if auth == nil || auth.ID == "" {
 		return nil, nil
 	}
+	stored := auth.Clone()
+	syncSchedulingAttributes(stored)
 	m.mu.Lock()
-	m.auths[auth.ID] = auth.Clone()
+	m.applyRestoredStateLocked(stored, true)
+	m.auths[auth.ID] = stored
 	m.mu.Unlock()
 	_ = m.persist(ctx, auth)
 	m.hook.OnAuthUpdated(ctx, auth.Clone())
 	return auth.Clone(), nil
 }
 
-// Load resets manager state from the backing store.
+// Load resets manager state from the backing store, restoring persisted runtime state when the
+// store implements RuntimeStateStore.
 func (m *Manager) Load(ctx context.Context) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -204,19 +301,40 @@ func (m *Manager) Load(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	m.loadRuntimeStateLocked(ctx)
 	m.auths = make(map[string]*Auth, len(items))
 	for _, auth := range items {
 		if auth == nil || auth.ID == "" {
 			continue
 		}
-		m.auths[auth.ID] = auth.Clone()
+		stored := auth.Clone()
+		syncSchedulingAttributes(stored)
+		// Loaded auths are usually registered again right away; leave the entry for that call.
+		m.applyRestoredStateLocked(stored, false)
+		m.auths[auth.ID] = stored
 	}
 	return nil
 }
 
 // Execute performs a non-streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// When every credential fails, the configured model fallbacks are tried in order.
 func (m *Manager) Execute(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
+	resp, err := m.executeModel(ctx, providers, req, opts)
+	if err == nil {
+		notifyServedModel(ctx, req.Model)
+		return resp, nil
+	}
+	if fallbackResp, ok := walkFallbacks(ctx, m, req.Model, err, func(fallbackProviders []string, model string) (cliproxyexecutor.Response, error) {
+		fallbackReq, fallbackOpts := fallbackRequest(req, opts, model)
+		return m.executeModel(ctx, fallbackProviders, fallbackReq, fallbackOpts)
+	}); ok {
+		return fallbackResp, nil
+	}
+	return resp, err
+}
+
+func (m *Manager) executeModel(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (cliproxyexecutor.Response, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "no provider supplied"}
@@ -264,7 +382,24 @@ func (m *Manager) ExecuteCount(ctx context.Context, providers []string, req clip
 
 // ExecuteStream performs a streaming execution using the configured selector and executor.
 // It supports multiple providers for the same model and round-robins the starting provider per model.
+// Errors surfaced before the first payload chunk fail over to the next auth or provider transparently,
+// and then to the configured model fallbacks.
 func (m *Manager) ExecuteStream(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
+	chunks, err := m.executeStreamModel(ctx, providers, req, opts)
+	if err == nil {
+		notifyServedModel(ctx, req.Model)
+		return chunks, nil
+	}
+	if fallbackChunks, ok := walkFallbacks(ctx, m, req.Model, err, func(fallbackProviders []string, model string) (<-chan cliproxyexecutor.StreamChunk, error) {
+		fallbackReq, fallbackOpts := fallbackRequest(req, opts, model)
+		return m.executeStreamModel(ctx, fallbackProviders, fallbackReq, fallbackOpts)
+	}); ok {
+		return fallbackChunks, nil
+	}
+	return nil, err
+}
+
+func (m *Manager) executeStreamModel(ctx context.Context, providers []string, req cliproxyexecutor.Request, opts cliproxyexecutor.Options) (<-chan cliproxyexecutor.StreamChunk, error) {
 	normalized := m.normalizeProviders(providers)
 	if len(normalized) == 0 {
 		return nil, &Error{Code: "provider_not_found", Message: "no provider supplied"}
@@ -291,6 +426,7 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "provider identifier is empty"}
 	}
 	tried := make(map[string]struct{})
+	attempt := 0
 	var lastErr error
 	for {
 		auth, executor, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
@@ -314,8 +450,19 @@ func (m *Manager) executeWithProvider(ctx context.Context, provider string, req
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
 		}
-		resp, errExec := executor.Execute(execCtx, auth, req, opts)
-		result := Result{AuthID: auth.ID, Provider: provider, Model: req.Model, Success: errExec == nil}
+		var resp cliproxyexecutor.Response
+		var errExec error
+		var started time.Time
+		for {
+			started = time.Now()
+			resp, errExec = executor.Execute(execCtx, auth, req, opts)
+			if errExec == nil || !m.awaitRetry(ctx, provider, req.Model, opts, auth, &attempt, errExec) {
+				break
+			}
+		}
+		m.load.end(auth.ID)
+		latency := time.Since(started)
+		result := Result{AuthID: auth.ID, Provider: provider, Model: req.Model, Success: errExec == nil, Latency: latency}
 		if errExec != nil {
 			result.Error = &Error{Message: errExec.Error()}
 			var se cliproxyexecutor.StatusError
@@ -336,6 +483,7 @@ func (m *Manager) executeCountWithProvider(ctx context.Context, provider string,
 		return cliproxyexecutor.Response{}, &Error{Code: "provider_not_found", Message: "provider identifier is empty"}
 	}
 	tried := make(map[string]struct{})
+	attempt := 0
 	var lastErr error
 	for {
 		auth, executor, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
@@ -359,7 +507,15 @@ func (m *Manager) executeCountWithProvider(ctx context.Context, provider string,
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
 		}
-		resp, errExec := executor.CountTokens(execCtx, auth, req, opts)
+		var resp cliproxyexecutor.Response
+		var errExec error
+		for {
+			resp, errExec = executor.CountTokens(execCtx, auth, req, opts)
+			if errExec == nil || !m.awaitRetry(ctx, provider, req.Model, opts, auth, &attempt, errExec) {
+				break
+			}
+		}
+		m.load.end(auth.ID)
 		result := Result{AuthID: auth.ID, Provider: provider, Model: req.Model, Success: errExec == nil}
 		if errExec != nil {
 			result.Error = &Error{Message: errExec.Error()}
@@ -381,6 +537,7 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 		return nil, &Error{Code: "provider_not_found", Message: "provider identifier is empty"}
 	}
 	tried := make(map[string]struct{})
+	attempt := 0
 	var lastErr error
 	for {
 		auth, executor, errPick := m.pickNext(ctx, provider, req.Model, opts, tried)
@@ -404,8 +561,23 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 			execCtx = context.WithValue(execCtx, roundTripperContextKey{}, rt)
 			execCtx = context.WithValue(execCtx, "cliproxy.roundtripper", rt)
 		}
-		chunks, errStream := executor.ExecuteStream(execCtx, auth, req, opts)
+		var chunks <-chan cliproxyexecutor.StreamChunk
+		var head []cliproxyexecutor.StreamChunk
+		var errStream error
+		var started time.Time
+		for {
+			started = time.Now()
+			chunks, errStream = executor.ExecuteStream(execCtx, auth, req, opts)
+			if errStream == nil {
+				head, errStream = awaitFirstPayload(chunks)
+			}
+			if errStream == nil || !m.awaitRetry(ctx, provider, req.Model, opts, auth, &attempt, errStream) {
+				break
+			}
+		}
+		firstByte := time.Since(started)
 		if errStream != nil {
+			m.load.end(auth.ID)
 			rerr := &Error{Message: errStream.Error()}
 			var se cliproxyexecutor.StatusError
 			if errors.As(errStream, &se) && se != nil {
@@ -417,8 +589,14 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 			continue
 		}
 		out := make(chan cliproxyexecutor.StreamChunk)
-		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamChunks <-chan cliproxyexecutor.StreamChunk) {
+		go func(streamCtx context.Context, streamAuth *Auth, streamProvider string, streamHead []cliproxyexecutor.StreamChunk, streamChunks <-chan cliproxyexecutor.StreamChunk) {
 			defer close(out)
+			defer m.load.end(streamAuth.ID)
+			for _, chunk := range streamHead {
+				if !forwardChunk(streamCtx, out, chunk, streamChunks) {
+					return
+				}
+			}
 			var failed bool
 			for chunk := range streamChunks {
 				if chunk.Err != nil && !failed {
@@ -430,16 +608,88 @@ func (m *Manager) executeStreamWithProvider(ctx context.Context, provider string
 					}
 					m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: req.Model, Success: false, Error: rerr})
 				}
-				out <- chunk
+				if !forwardChunk(streamCtx, out, chunk, streamChunks) {
+					return
+				}
 			}
 			if !failed {
-				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: req.Model, Success: true})
+				m.MarkResult(streamCtx, Result{AuthID: streamAuth.ID, Provider: streamProvider, Model: req.Model, Success: true, Latency: time.Since(started), FirstByte: firstByte})
 			}
-		}(execCtx, auth.Clone(), provider, chunks)
+		}(execCtx, auth.Clone(), provider, head, chunks)
 		return out, nil
 	}
 }
 
+// forwardChunk sends chunk to out unless ctx is done first. When the client has gone away it drains
+// the rest of chunks in the background, so the executor can finish once its request is cancelled,
+// and reports false.
+func forwardChunk(ctx context.Context, out chan<- cliproxyexecutor.StreamChunk, chunk cliproxyexecutor.StreamChunk, chunks <-chan cliproxyexecutor.StreamChunk) bool {
+	select {
+	case out <- chunk:
+		return true
+	case <-ctx.Done():
+		go func() {
+			for range chunks {
+			}
+		}()
+		return false
+	}
+}
+
+// awaitFirstPayload buffers stream chunks until the first non-empty payload arrives. An error seen
+// before that point is returned instead, so the caller can fail over while the client has not yet
+// received a single byte. The remainder of a failed stream is drained in the background.
+func awaitFirstPayload(chunks <-chan cliproxyexecutor.StreamChunk) ([]cliproxyexecutor.StreamChunk, error) {
+	var head []cliproxyexecutor.StreamChunk
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			go func() {
+				for range chunks {
+				}
+			}()
+			return nil, chunk.Err
+		}
+		head = append(head, chunk)
+		if len(chunk.Payload) > 0 {
+			break
+		}
+	}
+	return head, nil
+}
+
+// awaitRetry reports whether a failed call should be re-attempted on the same auth and, if so,
+// sleeps for the policy delay and charges the rate limits of auth. The shared attempt counter
+// enforces the per-request budget.
+func (m *Manager) awaitRetry(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auth *Auth, attempt *int, err error) bool {
+	m.mu.RLock()
+	policy := m.retryPolicy
+	m.mu.RUnlock()
+	delay, retry := policy.retryDelay(provider, *attempt, err)
+	if !retry {
+		return false
+	}
+	// Retries are charged against the rate limits of auth like the first attempt. When its budget
+	// does not recover within the backoff cap, the request moves on to another auth instead.
+	tokens := m.requestTokens(provider, model, opts)
+	if wait := m.limits.wait(auth, model, tokens, time.Now()); wait > delay {
+		if wait > policy.maxBackoff() {
+			log.Debugf("not retrying %s request for model %s with auth %s: rate limited for %s", provider, model, auth.ID, wait)
+			return false
+		}
+		delay = wait
+	}
+	*attempt++
+	log.Debugf("retrying %s request for model %s with auth %s in %s (attempt %d): %v", provider, model, auth.ID, delay, *attempt, err)
+	if waitForRetry(ctx, delay) != nil {
+		return false
+	}
+	if !m.limits.tryTake(auth, model, tokens, time.Now()) {
+		log.Debugf("not retrying %s request for model %s with auth %s: rate limit budget taken", provider, model, auth.ID)
+		return false
+	}
+	return true
+}
+
 func (m *Manager) normalizeProviders(providers []string) []string {
 	if len(providers) == 0 {
 		return nil
@@ -500,6 +750,11 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	if result.AuthID == "" {
 		return
 	}
+	if result.Success && result.Latency > 0 {
+		m.load.observe(result.AuthID, result.FirstByte, result.Latency, time.Now())
+	} else if !result.Success && failureCountsAgainstLatency(result.Error) {
+		m.load.observeFailure(result.AuthID, result.Latency, time.Now())
+	}
 
 	shouldResumeModel := false
 	shouldSuspendModel := false
@@ -510,6 +765,7 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 	m.mu.Lock()
 	if auth, ok := m.auths[result.AuthID]; ok && auth != nil {
 		now := time.Now()
+		stateBefore := captureRuntimeState(auth, now)
 
 		if result.Success {
 			if result.Model != "" {
@@ -582,6 +838,10 @@ func (m *Manager) MarkResult(ctx context.Context, result Result) {
 				applyAuthFailureState(auth, result.Error, now)
 			}
 		}
+		m.recordBreakers(ctx, auth, result, now)
+		if !reflect.DeepEqual(stateBefore, captureRuntimeState(auth, now)) {
+			m.markRuntimeStateDirty()
+		}
 
 		_ = m.persist(ctx, auth)
 	}
@@ -833,39 +1093,116 @@ func (m *Manager) GetByID(id string) (*Auth, bool) {
 	return auth.Clone(), true
 }
 
+// pickNext selects the next auth for provider, reserving one of its concurrency slots and charging
+// its rate limits. When every remaining candidate is only held back by max concurrency or rate
+// limits, the request queues until one frees up or the queue wait timeout elapses. Callers must
+// release the slot via m.load.end.
 func (m *Manager) pickNext(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}) (*Auth, ProviderExecutor, error) {
+	tokens := m.requestTokens(provider, model, opts)
+	var deadline time.Time
+	for {
+		released := m.load.releaseSignal()
+		auth, executor, wait, err := m.pickNextOnce(ctx, provider, model, opts, tried, tokens)
+		if errors.Is(err, errSlotTaken) {
+			continue
+		}
+		if auth != nil && breakerProbeDue(auth, model, time.Now()) && !m.claimBreakerProbe(auth.ID, model) {
+			// Another request is already probing this auth; select again.
+			m.load.end(auth.ID)
+			m.limits.refund(auth, model, tokens, time.Now())
+			continue
+		}
+		if !wait.queued {
+			return auth, executor, err
+		}
+		if deadline.IsZero() {
+			deadline = time.Now().Add(m.ConcurrencyWait())
+		}
+		if errWait := waitForSlot(ctx, released, deadline, wait); errWait != nil {
+			return nil, nil, errWait
+		}
+	}
+}
+
+// pickNextOnce makes a single selection attempt. A queued wait reports that candidates exist but
+// all usable ones are at their max concurrency or out of rate limit budget.
+func (m *Manager) pickNextOnce(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, tried map[string]struct{}, tokens int64) (*Auth, ProviderExecutor, pickWait, error) {
 	m.mu.RLock()
+	defer m.mu.RUnlock()
 	executor, okExecutor := m.executors[provider]
 	if !okExecutor {
-		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "executor_not_found", Message: "executor not registered"}
+		return nil, nil, pickWait{}, &Error{Code: "executor_not_found", Message: "executor not registered"}
 	}
+	now := time.Now()
+	group := CredentialGroupFromContext(ctx)
 	candidates := make([]*Auth, 0, len(m.auths))
+	var busy, limited []*Auth
+	var retryIn time.Duration
 	for _, candidate := range m.auths {
-		if candidate.Provider != provider || candidate.Disabled {
+		if candidate.Provider != provider || candidate.Disabled || !authInGroup(candidate, group) {
 			continue
 		}
 		if _, used := tried[candidate.ID]; used {
 			continue
 		}
+		if m.load.saturated(candidate.ID, authMaxConcurrency(candidate)) {
+			busy = append(busy, candidate)
+			continue
+		}
+		if wait := m.limits.wait(candidate, model, tokens, now); wait > 0 {
+			limited = append(limited, candidate)
+			if retryIn == 0 || wait < retryIn {
+				retryIn = wait
+			}
+			continue
+		}
 		candidates = append(candidates, candidate)
 	}
-	if len(candidates) == 0 {
-		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "no auth available"}
-	}
-	selected, errPick := m.selector.Pick(ctx, provider, model, opts, candidates)
-	if errPick != nil {
-		m.mu.RUnlock()
-		return nil, nil, errPick
+	if len(candidates) == 0 && len(busy) == 0 && len(limited) == 0 {
+		return nil, nil, pickWait{}, &Error{Code: "auth_not_found", Message: "no auth available"}
+	}
+	var selected *Auth
+	var errPick error
+	if len(candidates) > 0 {
+		selected, errPick = m.selector.Pick(ctx, provider, model, opts, candidates)
+	}
+	if len(candidates) == 0 || errPick != nil {
+		// Queue only when a held-back credential would otherwise be usable for this model.
+		wait := pickWait{}
+		if len(busy) > 0 {
+			if _, errBusy := availableAuths(provider, model, busy); errBusy == nil {
+				wait.queued = true
+			} else if errPick == nil {
+				errPick = errBusy
+			}
+		}
+		if len(limited) > 0 {
+			if _, errLimited := availableAuths(provider, model, limited); errLimited == nil {
+				wait.rateLimitedOnly = !wait.queued
+				wait.queued = true
+				wait.retryIn = retryIn
+			} else if errPick == nil {
+				errPick = errLimited
+			}
+		}
+		if wait.queued {
+			return nil, nil, wait, nil
+		}
+		return nil, nil, pickWait{}, errPick
 	}
 	if selected == nil {
-		m.mu.RUnlock()
-		return nil, nil, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
+		return nil, nil, pickWait{}, &Error{Code: "auth_not_found", Message: "selector returned no auth"}
 	}
-	authCopy := selected.Clone()
-	m.mu.RUnlock()
-	return authCopy, executor, nil
+	if !m.load.tryBegin(selected.ID, authMaxConcurrency(selected)) {
+		// Another request took the last slot since the saturation check; select again.
+		return nil, nil, pickWait{}, errSlotTaken
+	}
+	if !m.limits.tryTake(selected, model, tokens, time.Now()) {
+		// Another request spent the remaining budget since the check; select again.
+		m.load.end(selected.ID)
+		return nil, nil, pickWait{}, errSlotTaken
+	}
+	return selected.Clone(), executor, pickWait{}, nil
 }
 
 func (m *Manager) persist(ctx context.Context, auth *Auth) error {
@@ -910,6 +1247,7 @@ func (m *Manager) StartAutoRefresh(parent context.Context, interval time.Duratio
 				return
 			case <-ticker.C:
 				m.checkRefreshes(ctx)
+				m.flushRuntimeStateIfDue(ctx, time.Now())
 			}
 		}
 	}()
@@ -1192,13 +1530,20 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	log.Debugf("refreshed %s, %s, %v", auth.Provider, auth.ID, err)
 	now := time.Now()
 	if err != nil {
+		var failed *Auth
 		m.mu.Lock()
 		if current := m.auths[id]; current != nil {
 			current.NextRefreshAfter = now.Add(refreshFailureBackoff)
 			current.LastError = &Error{Message: err.Error()}
 			m.auths[id] = current
+			failed = current.Clone()
 		}
 		m.mu.Unlock()
+		if failed != nil {
+			if hook, ok := m.hook.(RefreshHook); ok {
+				hook.OnRefresh(ctx, failed, err)
+			}
+		}
 		return
 	}
 	if updated == nil {
@@ -1214,6 +1559,9 @@ func (m *Manager) refreshAuth(ctx context.Context, id string) {
 	updated.LastError = nil
 	updated.UpdatedAt = now
 	_, _ = m.Update(ctx, updated)
+	if hook, ok := m.hook.(RefreshHook); ok {
+		hook.OnRefresh(ctx, updated.Clone(), nil)
+	}
 }
 
 func (m *Manager) executorFor(provider string) ProviderExecutor {
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// Attribute keys for per-credential rate limits, applied across all models of the credential.
+const (
+	AttributeRPM = "rpm"
+	AttributeTPM = "tpm"
+)
+
+// RateLimitRule limits every credential of a provider, optionally for a single model only.
+type RateLimitRule struct {
+	// Provider is the provider identifier the rule applies to.
+	Provider string
+	// Model restricts the rule to one model with its own budget. Empty covers all models together.
+	Model string
+	// RPM is the requests-per-minute budget per credential. Zero disables it.
+	RPM int
+	// TPM is the estimated input-tokens-per-minute budget per credential. Zero disables it.
+	TPM int
+}
+
+// TokenEstimator approximates the input tokens of a request in the client's source format.
+type TokenEstimator func(model string, opts cliproxyexecutor.Options) int64
+
+// SetRateLimits replaces the provider-level rate limit rules and resets all buckets.
+func (m *Manager) SetRateLimits(rules []RateLimitRule) {
+	m.limits.setRules(rules)
+}
+
+// SetTokenEstimator installs the estimator used to charge TPM budgets before dispatch.
+func (m *Manager) SetTokenEstimator(estimator TokenEstimator) {
+	m.mu.Lock()
+	m.tokenEstimator = estimator
+	m.mu.Unlock()
+}
+
+// requestTokens estimates the input tokens of a request dispatched to provider. It runs before
+// selection takes any lock, and skips the estimate (returning zero) when no TPM budget applies.
+func (m *Manager) requestTokens(provider, model string, opts cliproxyexecutor.Options) int64 {
+	m.mu.RLock()
+	estimator := m.tokenEstimator
+	needed := false
+	for _, auth := range m.auths {
+		if auth.Provider == provider && authTPM(auth) > 0 {
+			needed = true
+			break
+		}
+	}
+	m.mu.RUnlock()
+	if !needed && !m.limits.hasTokenRule(provider, model) {
+		return 0
+	}
+	var estimated int64
+	if estimator != nil {
+		estimated = estimator(model, opts)
+	} else {
+		// Roughly four bytes per token when no tokenizer is wired in.
+		estimated = int64(len(opts.OriginalRequest) / 4)
+	}
+	return max(estimated, 0)
+}
+
+// authTPM returns the per-credential TPM budget of auth, zero when it has none.
+func authTPM(auth *Auth) int {
+	if auth == nil || auth.Attributes == nil {
+		return 0
+	}
+	tpm, err := strconv.Atoi(strings.TrimSpace(auth.Attributes[AttributeTPM]))
+	if err != nil || tpm < 0 {
+		return 0
+	}
+	return tpm
+}
+
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	perSec   float64
+	last     time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	capacity := float64(perMinute)
+	return &tokenBucket{capacity: capacity, tokens: capacity, perSec: capacity / 60, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.perSec)
+		b.last = now
+	}
+}
+
+// waitFor returns how long until cost can be taken. Costs above capacity only need a full bucket.
+func (b *tokenBucket) waitFor(cost float64, now time.Time) time.Duration {
+	b.refill(now)
+	cost = math.Min(cost, b.capacity)
+	if b.tokens >= cost {
+		return 0
+	}
+	return time.Duration((cost - b.tokens) / b.perSec * float64(time.Second))
+}
+
+func (b *tokenBucket) take(cost float64) {
+	b.tokens -= math.Min(cost, b.capacity)
+}
+
+func (b *tokenBucket) give(cost float64) {
+	b.tokens = math.Min(b.capacity, b.tokens+math.Min(cost, b.capacity))
+}
+
+type limitSpec struct {
+	key       string
+	perMinute int
+	tokens    bool
+}
+
+// rateLimiter holds token buckets for per-credential and per-credential-per-model limits.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rules   []RateLimitRule
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*tokenBucket)}
+}
+
+func (l *rateLimiter) setRules(rules []RateLimitRule) {
+	cloned := make([]RateLimitRule, 0, len(rules))
+	for _, rule := range rules {
+		rule.Provider = strings.ToLower(strings.TrimSpace(rule.Provider))
+		rule.Model = strings.TrimSpace(rule.Model)
+		if rule.Provider == "" || (rule.RPM <= 0 && rule.TPM <= 0) {
+			continue
+		}
+		cloned = append(cloned, rule)
+	}
+	l.mu.Lock()
+	l.rules = cloned
+	l.buckets = make(map[string]*tokenBucket)
+	l.mu.Unlock()
+}
+
+// specsLocked lists the budgets that apply to a request for model on auth.
+func (l *rateLimiter) specsLocked(auth *Auth, model string) []limitSpec {
+	var specs []limitSpec
+	if auth.Attributes != nil {
+		if rpm, err := strconv.Atoi(strings.TrimSpace(auth.Attributes[AttributeRPM])); err == nil && rpm > 0 {
+			specs = append(specs, limitSpec{key: auth.ID + "|rpm", perMinute: rpm})
+		}
+		if tpm := authTPM(auth); tpm > 0 {
+			specs = append(specs, limitSpec{key: auth.ID + "|tpm", perMinute: tpm, tokens: true})
+		}
+	}
+	for i, rule := range l.rules {
+		if rule.Provider != strings.ToLower(auth.Provider) || (rule.Model != "" && rule.Model != model) {
+			continue
+		}
+		prefix := fmt.Sprintf("%s|rule%d|%s", auth.ID, i, rule.Model)
+		if rule.RPM > 0 {
+			specs = append(specs, limitSpec{key: prefix + "|rpm", perMinute: rule.RPM})
+		}
+		if rule.TPM > 0 {
+			specs = append(specs, limitSpec{key: prefix + "|tpm", perMinute: rule.TPM, tokens: true})
+		}
+	}
+	return specs
+}
+
+// hasTokenRule reports whether a provider rule with a TPM budget applies to requests for model.
+func (l *rateLimiter) hasTokenRule(provider, model string) bool {
+	provider = strings.ToLower(provider)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, rule := range l.rules {
+		if rule.TPM > 0 && rule.Provider == provider && (rule.Model == "" || rule.Model == model) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *rateLimiter) bucketLocked(spec limitSpec, now time.Time) *tokenBucket {
+	bucket, ok := l.buckets[spec.key]
+	if !ok || bucket.capacity != float64(spec.perMinute) {
+		bucket = newTokenBucket(spec.perMinute, now)
+		l.buckets[spec.key] = bucket
+	}
+	return bucket
+}
+
+// costs resolves the applicable budgets and their cost for a request on model estimated at tokens
+// input tokens.
+func (l *rateLimiter) costs(auth *Auth, model string, tokens int64) ([]limitSpec, []float64) {
+	l.mu.Lock()
+	specs := l.specsLocked(auth, model)
+	l.mu.Unlock()
+	costs := make([]float64, len(specs))
+	for i, spec := range specs {
+		costs[i] = 1
+		if spec.tokens {
+			costs[i] = float64(tokens)
+		}
+	}
+	return specs, costs
+}
+
+// wait returns how long until auth has budget for a request on model, zero when it has now.
+func (l *rateLimiter) wait(auth *Auth, model string, tokens int64, now time.Time) time.Duration {
+	specs, costs := l.costs(auth, model, tokens)
+	if len(specs) == 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var longest time.Duration
+	for i, spec := range specs {
+		if wait := l.bucketLocked(spec, now).waitFor(costs[i], now); wait > longest {
+			longest = wait
+		}
+	}
+	return longest
+}
+
+// tryTake charges every budget of auth for a request on model, or none when any is short.
+func (l *rateLimiter) tryTake(auth *Auth, model string, tokens int64, now time.Time) bool {
+	specs, costs := l.costs(auth, model, tokens)
+	if len(specs) == 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, spec := range specs {
+		if l.bucketLocked(spec, now).waitFor(costs[i], now) > 0 {
+			return false
+		}
+	}
+	for i, spec := range specs {
+		l.buckets[spec.key].take(costs[i])
+	}
+	return true
+}
+
+// refund returns what tryTake charged auth for a request on model that was never dispatched.
+func (l *rateLimiter) refund(auth *Auth, model string, tokens int64, now time.Time) {
+	specs, costs := l.costs(auth, model, tokens)
+	if len(specs) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, spec := range specs {
+		l.bucketLocked(spec, now).give(costs[i])
+	}
+}
@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+const (
+	defaultRetryInitialBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff     = 30 * time.Second
+)
+
+// DefaultRetryStatusCodes lists the upstream statuses retried when no override is configured.
+var DefaultRetryStatusCodes = []int{
+	http.StatusForbidden,
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy controls how the manager re-attempts failed upstream calls on the same credential
+// before failing over to the next one.
+type RetryPolicy struct {
+	// MaxRetries is the number of additional attempts allowed per request.
+	MaxRetries int
+	// InitialBackoff is the delay before the first retry; later retries double it.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed delay. Upstream Retry-After hints above this cap skip the retry.
+	MaxBackoff time.Duration
+	// StatusCodes lists retriable upstream statuses. Empty means DefaultRetryStatusCodes.
+	StatusCodes []int
+	// Providers holds per-provider overrides keyed by provider identifier.
+	Providers map[string]ProviderRetryPolicy
+}
+
+// ProviderRetryPolicy overrides the retry count and retriable statuses for one provider.
+type ProviderRetryPolicy struct {
+	// MaxRetries overrides RetryPolicy.MaxRetries when non-nil.
+	MaxRetries *int
+	// StatusCodes overrides RetryPolicy.StatusCodes when non-empty.
+	StatusCodes []int
+}
+
+// SetRetryPolicy replaces the retry policy applied to subsequent executions.
+func (m *Manager) SetRetryPolicy(policy RetryPolicy) {
+	cloned := policy.clone()
+	m.mu.Lock()
+	m.retryPolicy = cloned
+	m.mu.Unlock()
+}
+
+// RetryPolicy returns a copy of the active retry policy.
+func (m *Manager) RetryPolicy() RetryPolicy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.retryPolicy.clone()
+}
+
+func (p RetryPolicy) clone() RetryPolicy {
+	out := p
+	out.StatusCodes = append([]int(nil), p.StatusCodes...)
+	if len(p.Providers) > 0 {
+		out.Providers = make(map[string]ProviderRetryPolicy, len(p.Providers))
+		for key, value := range p.Providers {
+			entry := ProviderRetryPolicy{StatusCodes: append([]int(nil), value.StatusCodes...)}
+			if value.MaxRetries != nil {
+				retries := *value.MaxRetries
+				entry.MaxRetries = &retries
+			}
+			out.Providers[strings.ToLower(strings.TrimSpace(key))] = entry
+		}
+	} else {
+		out.Providers = nil
+	}
+	return out
+}
+
+// maxRetriesFor resolves the retry budget for a provider.
+func (p RetryPolicy) maxRetriesFor(provider string) int {
+	retries := p.MaxRetries
+	if override, ok := p.Providers[provider]; ok && override.MaxRetries != nil {
+		retries = *override.MaxRetries
+	}
+	if retries < 0 {
+		return 0
+	}
+	return retries
+}
+
+// retriable reports whether the upstream status should trigger another attempt for the provider.
+func (p RetryPolicy) retriable(provider string, status int) bool {
+	if status <= 0 {
+		return false
+	}
+	codes := p.StatusCodes
+	if override, ok := p.Providers[provider]; ok && len(override.StatusCodes) > 0 {
+		codes = override.StatusCodes
+	}
+	if len(codes) == 0 {
+		codes = DefaultRetryStatusCodes
+	}
+	for _, code := range codes {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the jittered exponential delay for the given zero-based retry attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	base := p.InitialBackoff
+	if base <= 0 {
+		base = defaultRetryInitialBackoff
+	}
+	maxDelay := p.maxBackoff()
+	if attempt > 16 {
+		attempt = 16
+	}
+	delay := base * time.Duration(1<<attempt)
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	// Full jitter over the upper half keeps retries spread out without collapsing to zero.
+	half := delay / 2
+	if half > 0 {
+		delay = half + time.Duration(rand.Int63n(int64(half)+1))
+	}
+	return delay
+}
+
+func (p RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff > 0 {
+		return p.MaxBackoff
+	}
+	return defaultRetryMaxBackoff
+}
+
+// retryDelay decides whether err warrants another attempt and how long to wait before it.
+func (p RetryPolicy) retryDelay(provider string, attempt int, err error) (time.Duration, bool) {
+	if err == nil || attempt >= p.maxRetriesFor(provider) {
+		return 0, false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, false
+	}
+	if !p.retriable(provider, statusCodeFromError(err)) {
+		return 0, false
+	}
+	delay := p.backoff(attempt)
+	if hint := retryAfterFromError(err); hint > 0 {
+		if hint > p.maxBackoff() {
+			return 0, false
+		}
+		if hint > delay {
+			delay = hint
+		}
+	}
+	return delay, true
+}
+
+// waitForRetry sleeps for delay unless ctx is cancelled first.
+func waitForRetry(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func statusCodeFromError(err error) int {
+	var se cliproxyexecutor.StatusError
+	if errors.As(err, &se) && se != nil {
+		return se.StatusCode()
+	}
+	return 0
+}
+
+// retryAfterFromError extracts an upstream Retry-After hint from executor or manager errors.
+func retryAfterFromError(err error) time.Duration {
+	var hinted interface{ RetryAfter() time.Duration }
+	if errors.As(err, &hinted) && hinted != nil {
+		if wait := hinted.RetryAfter(); wait > 0 {
+			return wait
+		}
+	}
+	var withHeaders interface{ Headers() http.Header }
+	if errors.As(err, &withHeaders) && withHeaders != nil {
+		if headers := withHeaders.Headers(); headers != nil {
+			if secs, errAtoi := strconv.Atoi(strings.TrimSpace(headers.Get("Retry-After"))); errAtoi == nil && secs > 0 {
+				return time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	runtimeStateVersion = 1
+	// runtimeStateFlushInterval bounds how often changed runtime state is written to the store.
+	runtimeStateFlushInterval = time.Minute
+	// runtimeStateRetention keeps recovered entries around long enough for their quota backoff
+	// level to matter; older entries are dropped as stale.
+	runtimeStateRetention = quotaBackoffMax
+)
+
+// runtimeState is the persisted availability state of one auth.
+type runtimeState struct {
+	Status         Status                 `json:"status,omitempty"`
+	StatusMessage  string                 `json:"status_message,omitempty"`
+	Unavailable    bool                   `json:"unavailable,omitempty"`
+	NextRetryAfter time.Time              `json:"next_retry_after"`
+	Quota          QuotaState             `json:"quota"`
+	LastError      *Error                 `json:"last_error,omitempty"`
+	ModelStates    map[string]*ModelState `json:"model_states,omitempty"`
+	Breaker        BreakerState           `json:"breaker"`
+}
+
+type runtimeStateDocument struct {
+	Version int                      `json:"version"`
+	SavedAt time.Time                `json:"saved_at"`
+	Auths   map[string]*runtimeState `json:"auths"`
+}
+
+func latestOf(times ...time.Time) time.Time {
+	var latest time.Time
+	for _, t := range times {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// runtimeStateStale reports whether a restriction ending at deadline no longer matters at now.
+func runtimeStateStale(deadline, now time.Time) bool {
+	return deadline.IsZero() || now.Sub(deadline) > runtimeStateRetention
+}
+
+func breakerStale(b BreakerState, now time.Time) bool {
+	if b.State != BreakerOpen && b.State != BreakerHalfOpen {
+		return true
+	}
+	return runtimeStateStale(b.NextProbeAt, now)
+}
+
+func modelStateStale(state *ModelState, now time.Time) bool {
+	if state == nil {
+		return true
+	}
+	if !breakerStale(state.Breaker, now) {
+		return false
+	}
+	return runtimeStateStale(latestOf(state.NextRetryAfter, state.Quota.NextRecoverAt), now)
+}
+
+// captureRuntimeState extracts the state worth persisting for a, or nil when nothing is.
+func captureRuntimeState(a *Auth, now time.Time) *runtimeState {
+	if a == nil {
+		return nil
+	}
+	state := pruneRuntimeState(&runtimeState{
+		Status:         a.Status,
+		StatusMessage:  a.StatusMessage,
+		Unavailable:    a.Unavailable,
+		NextRetryAfter: a.NextRetryAfter,
+		Quota:          a.Quota,
+		LastError:      a.LastError,
+		ModelStates:    a.ModelStates,
+		Breaker:        a.Breaker,
+	}, now)
+	if state == nil {
+		return nil
+	}
+	state.LastError = cloneError(state.LastError)
+	for model, modelState := range state.ModelStates {
+		state.ModelStates[model] = modelState.Clone()
+	}
+	return state
+}
+
+// pruneRuntimeState returns the parts of a restored state that are not stale at now, or nil when
+// nothing is left.
+func pruneRuntimeState(state *runtimeState, now time.Time) *runtimeState {
+	if state == nil {
+		return nil
+	}
+	pruned := &runtimeState{}
+	keep := false
+	if !runtimeStateStale(latestOf(state.NextRetryAfter, state.Quota.NextRecoverAt), now) {
+		pruned.Status = state.Status
+		pruned.StatusMessage = state.StatusMessage
+		pruned.Unavailable = state.Unavailable
+		pruned.NextRetryAfter = state.NextRetryAfter
+		pruned.Quota = state.Quota
+		pruned.LastError = state.LastError
+		keep = true
+	}
+	if !breakerStale(state.Breaker, now) {
+		pruned.Breaker = state.Breaker
+		keep = true
+	}
+	for model, modelState := range state.ModelStates {
+		if modelStateStale(modelState, now) {
+			continue
+		}
+		if pruned.ModelStates == nil {
+			pruned.ModelStates = make(map[string]*ModelState)
+		}
+		pruned.ModelStates[model] = modelState
+		keep = true
+	}
+	if !keep {
+		return nil
+	}
+	return pruned
+}
+
+// applyRuntimeState restores the still relevant parts of state onto a.
+func applyRuntimeState(a *Auth, state *runtimeState, now time.Time) {
+	if a == nil || state == nil || a.Disabled || a.Status == StatusDisabled {
+		return
+	}
+	if !runtimeStateStale(latestOf(state.NextRetryAfter, state.Quota.NextRecoverAt), now) {
+		if state.Status != "" {
+			a.Status = state.Status
+		}
+		a.StatusMessage = state.StatusMessage
+		a.Unavailable = state.Unavailable
+		a.NextRetryAfter = state.NextRetryAfter
+		a.Quota = state.Quota
+		a.LastError = cloneError(state.LastError)
+	}
+	if !breakerStale(state.Breaker, now) {
+		a.Breaker = state.Breaker
+		// The probe in flight at shutdown is gone; let the next request probe.
+		a.Breaker.release(now)
+	}
+	for model, modelState := range state.ModelStates {
+		if modelStateStale(modelState, now) {
+			continue
+		}
+		if a.ModelStates == nil {
+			a.ModelStates = make(map[string]*ModelState)
+		}
+		restored := modelState.Clone()
+		restored.Breaker.release(now)
+		a.ModelStates[model] = restored
+	}
+	if len(a.ModelStates) > 0 {
+		updateAggregatedAvailability(a, now)
+	}
+}
+
+// loadRuntimeStateLocked reads the persisted runtime state from the store. Callers hold m.mu.
+func (m *Manager) loadRuntimeStateLocked(ctx context.Context) {
+	m.restoredState = nil
+	stateStore, ok := m.store.(RuntimeStateStore)
+	if !ok {
+		return
+	}
+	data, err := stateStore.LoadRuntimeState(ctx)
+	if err != nil {
+		log.Warnf("failed to load runtime auth state: %v", err)
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+	var doc runtimeStateDocument
+	if err = json.Unmarshal(data, &doc); err != nil {
+		log.Warnf("failed to parse runtime auth state: %v", err)
+		return
+	}
+	if doc.Version != runtimeStateVersion {
+		log.Warnf("ignoring runtime auth state with unsupported version %d", doc.Version)
+		return
+	}
+	m.restoredState = doc.Auths
+}
+
+// applyRestoredStateLocked restores persisted runtime state onto an auth registered after a restart.
+// Each entry is consumed by the first Register or Update for its auth. Callers hold m.mu.
+func (m *Manager) applyRestoredStateLocked(a *Auth, consume bool) {
+	if a == nil || len(m.restoredState) == 0 {
+		return
+	}
+	state, ok := m.restoredState[a.ID]
+	if !ok {
+		return
+	}
+	applyRuntimeState(a, state, time.Now())
+	if consume {
+		delete(m.restoredState, a.ID)
+	}
+}
+
+// DiscardRestoredRuntimeState drops persisted runtime state that no registered auth has claimed.
+// Call it once the auths known at startup are registered, so state of auths deleted while the
+// proxy was down is not carried over forever.
+func (m *Manager) DiscardRestoredRuntimeState() {
+	m.mu.Lock()
+	dropped := len(m.restoredState)
+	m.restoredState = nil
+	m.mu.Unlock()
+	if dropped > 0 {
+		log.Debugf("discarded runtime state of %d unknown auths", dropped)
+		m.markRuntimeStateDirty()
+	}
+}
+
+// markRuntimeStateDirty schedules the runtime state for the next flush.
+func (m *Manager) markRuntimeStateDirty() {
+	m.stateDirty.Store(true)
+}
+
+// FlushRuntimeState writes the runtime availability state of every auth to the store when it
+// implements RuntimeStateStore. Nothing is written when the state equals the last one written.
+func (m *Manager) FlushRuntimeState(ctx context.Context) error {
+	m.mu.RLock()
+	stateStore, ok := m.store.(RuntimeStateStore)
+	if !ok {
+		m.mu.RUnlock()
+		return nil
+	}
+	now := time.Now()
+	doc := runtimeStateDocument{Version: runtimeStateVersion, SavedAt: now, Auths: make(map[string]*runtimeState)}
+	for id, a := range m.auths {
+		if state := captureRuntimeState(a, now); state != nil {
+			doc.Auths[id] = state
+		}
+	}
+	// Keep restored entries whose auth has not been registered yet, as long as they still matter.
+	for id, state := range m.restoredState {
+		if _, exists := doc.Auths[id]; exists {
+			continue
+		}
+		if state = pruneRuntimeState(state, now); state != nil {
+			doc.Auths[id] = state
+		}
+	}
+	m.stateDirty.Store(false)
+	auths, err := json.Marshal(doc.Auths)
+	m.mu.RUnlock()
+	if err != nil {
+		m.stateDirty.Store(true)
+		return fmt.Errorf("marshal runtime auth state: %w", err)
+	}
+	m.stateMu.Lock()
+	defer m.stateMu.Unlock()
+	if m.stateSavedAuths != nil && bytes.Equal(auths, m.stateSavedAuths) {
+		return nil
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		m.stateDirty.Store(true)
+		return fmt.Errorf("marshal runtime auth state: %w", err)
+	}
+	if err = stateStore.SaveRuntimeState(ctx, data); err != nil {
+		m.stateDirty.Store(true)
+		return err
+	}
+	m.stateSavedAuths = auths
+	return nil
+}
+
+// flushRuntimeStateIfDue writes changed runtime state at most once per runtimeStateFlushInterval.
+func (m *Manager) flushRuntimeStateIfDue(ctx context.Context, now time.Time) {
+	if !m.stateDirty.Load() {
+		return
+	}
+	m.stateMu.Lock()
+	if now.Sub(m.stateFlushedAt) < runtimeStateFlushInterval {
+		m.stateMu.Unlock()
+		return
+	}
+	m.stateFlushedAt = now
+	m.stateMu.Unlock()
+	if err := m.FlushRuntimeState(ctx); err != nil {
+		log.Warnf("failed to persist runtime auth state: %v", err)
+	}
+}
@@ -11,3 +11,12 @@ type Store interface {
 	// Delete removes the auth record identified by id.
 	Delete(ctx context.Context, id string) error
 }
+
+// RuntimeStateStore is optionally implemented by stores that can also persist the runtime
+// availability state of auths (cooldowns, quota backoff, circuit breakers) across restarts.
+type RuntimeStateStore interface {
+	// LoadRuntimeState returns the last saved state document, or nil when none exists.
+	LoadRuntimeState(ctx context.Context) ([]byte, error)
+	// SaveRuntimeState replaces the saved state document.
+	SaveRuntimeState(ctx context.Context, data []byte) error
+}
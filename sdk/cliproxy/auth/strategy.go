@@ -0,0 +1,224 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	cliproxyexecutor "github.com/router-for-me/CLIProxyAPI/v6/sdk/cliproxy/executor"
+)
+
+// Selection strategies understood by NewSelector.
+const (
+	SelectionStrategyRoundRobin = "round-robin"
+	SelectionStrategyPriority   = "priority"
+	SelectionStrategyWeighted   = "weighted"
+	SelectionStrategyFillFirst  = "fill-first"
+	SelectionStrategyLatency    = "latency"
+	SelectionStrategyAffinity   = "affinity"
+)
+
+// Attribute keys consulted by the scheduling selectors.
+const (
+	// AttributePriority ranks credentials; higher values are preferred. Missing means 0.
+	AttributePriority = "priority"
+	// AttributeWeight sets the relative share of traffic for weighted selection. Missing means 1.
+	AttributeWeight = "weight"
+)
+
+// NormalizeSelectionStrategy canonicalises a strategy name, mapping empty input to round-robin.
+func NormalizeSelectionStrategy(strategy string) string {
+	normalized := strings.ToLower(strings.TrimSpace(strategy))
+	normalized = strings.ReplaceAll(normalized, "_", "-")
+	switch normalized {
+	case "", "roundrobin":
+		return SelectionStrategyRoundRobin
+	case "fillfirst":
+		return SelectionStrategyFillFirst
+	case "least-latency", "load":
+		return SelectionStrategyLatency
+	case "sticky", "session":
+		return SelectionStrategyAffinity
+	}
+	return normalized
+}
+
+// NewSelector builds the selector for the named strategy.
+func NewSelector(strategy string) (Selector, error) {
+	switch NormalizeSelectionStrategy(strategy) {
+	case SelectionStrategyRoundRobin:
+		return &RoundRobinSelector{}, nil
+	case SelectionStrategyPriority:
+		return &PrioritySelector{}, nil
+	case SelectionStrategyWeighted:
+		return &WeightedSelector{}, nil
+	case SelectionStrategyFillFirst:
+		return &FillFirstSelector{}, nil
+	case SelectionStrategyLatency:
+		return &LatencySelector{}, nil
+	case SelectionStrategyAffinity:
+		return &AffinitySelector{}, nil
+	default:
+		return nil, fmt.Errorf("unknown selection strategy %q", strategy)
+	}
+}
+
+// PrioritySelector only uses the highest-priority tier of available credentials and round-robins
+// within it. Lower tiers take over once every credential above them is blocked.
+type PrioritySelector struct {
+	tier RoundRobinSelector
+}
+
+// Pick selects the next available auth from the highest available priority tier.
+func (s *PrioritySelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	available, err := availableAuths(provider, model, auths)
+	if err != nil {
+		return nil, err
+	}
+	return s.tier.Pick(ctx, provider, model, opts, topPriorityTier(available))
+}
+
+// WeightedSelector picks a random available credential with probability proportional to its weight.
+type WeightedSelector struct{}
+
+// Pick selects an available auth using weighted random choice.
+func (s *WeightedSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	available, err := availableAuths(provider, model, auths)
+	if err != nil {
+		return nil, err
+	}
+	// Sort so the same weights map to the same ranges regardless of candidate order.
+	sort.Slice(available, func(i, j int) bool { return available[i].ID < available[j].ID })
+	total := 0
+	for _, candidate := range available {
+		total += authWeight(candidate)
+	}
+	roll := rand.Intn(total)
+	for _, candidate := range available {
+		roll -= authWeight(candidate)
+		if roll < 0 {
+			return candidate, nil
+		}
+	}
+	return available[len(available)-1], nil
+}
+
+// FillFirstSelector keeps using the same credential until it becomes unavailable, preferring
+// higher priorities and then lower IDs, so quota is drained one account at a time.
+type FillFirstSelector struct{}
+
+// Pick selects the first available auth in priority and ID order.
+func (s *FillFirstSelector) Pick(ctx context.Context, provider, model string, opts cliproxyexecutor.Options, auths []*Auth) (*Auth, error) {
+	_ = ctx
+	_ = opts
+	available, err := availableAuths(provider, model, auths)
+	if err != nil {
+		return nil, err
+	}
+	best := available[0]
+	for _, candidate := range available[1:] {
+		if pc, pb := authPriority(candidate), authPriority(best); pc > pb || (pc == pb && candidate.ID < best.ID) {
+			best = candidate
+		}
+	}
+	return best, nil
+}
+
+func topPriorityTier(auths []*Auth) []*Auth {
+	if len(auths) == 0 {
+		return auths
+	}
+	top := authPriority(auths[0])
+	for _, candidate := range auths[1:] {
+		if p := authPriority(candidate); p > top {
+			top = p
+		}
+	}
+	tier := make([]*Auth, 0, len(auths))
+	for _, candidate := range auths {
+		if authPriority(candidate) == top {
+			tier = append(tier, candidate)
+		}
+	}
+	return tier
+}
+
+func authPriority(a *Auth) int {
+	if a == nil || a.Attributes == nil {
+		return 0
+	}
+	priority, err := strconv.Atoi(strings.TrimSpace(a.Attributes[AttributePriority]))
+	if err != nil {
+		return 0
+	}
+	return priority
+}
+
+func authWeight(a *Auth) int {
+	if a == nil || a.Attributes == nil {
+		return 1
+	}
+	weight, err := strconv.Atoi(strings.TrimSpace(a.Attributes[AttributeWeight]))
+	if err != nil || weight <= 0 {
+		return 1
+	}
+	return weight
+}
+
+// syncSchedulingAttributes copies priority, weight, max_concurrency, rpm, tpm and group set in auth file metadata into
+// attributes so selectors see a single source regardless of where the credential came from.
+func syncSchedulingAttributes(a *Auth) {
+	if a == nil || len(a.Metadata) == 0 {
+		return
+	}
+	for _, key := range []string{AttributePriority, AttributeWeight, AttributeMaxConcurrency, AttributeRPM, AttributeTPM, AttributeGroup} {
+		raw, ok := a.Metadata[key]
+		if !ok {
+			continue
+		}
+		var value string
+		switch v := raw.(type) {
+		case float64:
+			value = strconv.Itoa(int(v))
+		case int:
+			value = strconv.Itoa(v)
+		case string:
+			value = strings.TrimSpace(v)
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				if text, okText := item.(string); okText && strings.TrimSpace(text) != "" {
+					items = append(items, strings.TrimSpace(text))
+				}
+			}
+			value = strings.Join(items, ",")
+		}
+		if value == "" {
+			continue
+		}
+		if a.Attributes == nil {
+			a.Attributes = make(map[string]string)
+		}
+		if _, exists := a.Attributes[key]; !exists {
+			a.Attributes[key] = value
+		}
+	}
+}
+
+// SetSelector swaps the credential selection strategy. A nil selector restores round-robin.
+func (m *Manager) SetSelector(selector Selector) {
+	if selector == nil {
+		selector = &RoundRobinSelector{}
+	}
+	if aware, ok := selector.(loadAwareSelector); ok {
+		aware.setLoadTracker(m.load)
+	}
+	m.mu.Lock()
+	m.selector = selector
+	m.mu.Unlock()
+}
@@ -14,9 +14,10 @@ import (
 
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/api"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/config"
+	"github.com/router-for-me/CLIProxyAPI/v6/internal/metrics"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/registry"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/runtime/executor"
-	_ "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
+	internalusage "github.com/router-for-me/CLIProxyAPI/v6/internal/usage"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/watcher"
 	"github.com/router-for-me/CLIProxyAPI/v6/internal/wsrelay"
 	sdkaccess "github.com/router-for-me/CLIProxyAPI/v6/sdk/access"
@@ -124,6 +125,7 @@ func (s *Service) ensureAuthUpdateQueue(ctx context.Context) {
 }
 
 func (s *Service) consumeAuthUpdates(ctx context.Context) {
+	initialSynced := false
 	for {
 		select {
 		case <-ctx.Done():
@@ -142,6 +144,12 @@ func (s *Service) consumeAuthUpdates(ctx context.Context) {
 					break labelDrain
 				}
 			}
+			// The first batch is the initial watcher sync; runtime state restored for auths it did
+			// not register belongs to auths that no longer exist.
+			if !initialSynced && s.coreManager != nil {
+				initialSynced = true
+				s.coreManager.DiscardRestoredRuntimeState()
+			}
 		}
 	}
 }
@@ -192,6 +200,7 @@ func (s *Service) ensureWebsocketGateway() {
 		LogWarnf:       log.Warnf,
 	}
 	s.wsGateway = wsrelay.NewManager(opts)
+	metrics.Default().SetSessionCounter(s.wsGateway)
 }
 
 func (s *Service) wsOnConnected(channelID string) {
@@ -385,6 +394,7 @@ func (s *Service) Run(ctx context.Context) error {
 	}
 
 	if s.coreManager != nil {
+		s.coreManager.SetTokenEstimator(executor.EstimateInputTokens)
 		if errLoad := s.coreManager.Load(ctx); errLoad != nil {
 			log.Warnf("failed to load auth store: %v", errLoad)
 		}
@@ -485,6 +495,9 @@ func (s *Service) Run(ctx context.Context) error {
 		watcherWrapper.SetAuthUpdateQueue(s.authUpdates)
 	}
 	watcherWrapper.SetConfig(s.cfg)
+	if s.server != nil {
+		watcherWrapper.SetTLSReloadCallback(s.server.ReloadTLSCertificates)
+	}
 
 	watcherCtx, watcherCancel := context.WithCancel(context.Background())
 	s.watcherCancel = watcherCancel
@@ -535,6 +548,9 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		}
 		if s.coreManager != nil {
 			s.coreManager.StopAutoRefresh()
+			if err := s.coreManager.FlushRuntimeState(ctx); err != nil {
+				log.Warnf("failed to persist runtime auth state: %v", err)
+			}
 		}
 		if s.watcher != nil {
 			if err := s.watcher.Stop(); err != nil {
@@ -569,6 +585,8 @@ func (s *Service) Shutdown(ctx context.Context) error {
 		}
 
 		usage.StopDefault()
+		internalusage.CloseStorage()
+		internalusage.CloseExport()
 	})
 	return shutdownErr
 }
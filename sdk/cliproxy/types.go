@@ -86,6 +86,7 @@ type WatcherWrapper struct {
 	setConfig      func(cfg *config.Config)
 	snapshotAuths  func() []*coreauth.Auth
 	setUpdateQueue func(queue chan<- watcher.AuthUpdate)
+	setTLSReload   func(fn func())
 }
 
 // Start proxies to the underlying watcher Start implementation.
@@ -133,3 +134,11 @@ func (w *WatcherWrapper) SetAuthUpdateQueue(queue chan<- watcher.AuthUpdate) {
 	}
 	w.setUpdateQueue(queue)
 }
+
+// SetTLSReloadCallback registers the function called when the TLS certificate files change.
+func (w *WatcherWrapper) SetTLSReloadCallback(fn func()) {
+	if w == nil || w.setTLSReload == nil {
+		return
+	}
+	w.setTLSReload(fn)
+}
@@ -17,7 +17,10 @@ type Record struct {
 	Source      string
 	RequestedAt time.Time
 	Failed      bool
-	Detail      Detail
+	// Partial marks a stream that ended before the provider reported usage; Detail is estimated
+	// from the prompt and the content forwarded to the client.
+	Partial bool
+	Detail  Detail
 }
 
 // Detail holds the token usage breakdown.
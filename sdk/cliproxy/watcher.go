@@ -28,5 +28,8 @@ func defaultWatcherFactory(configPath, authDir string, reload func(*config.Confi
 		setUpdateQueue: func(queue chan<- watcher.AuthUpdate) {
 			w.SetAuthUpdateQueue(queue)
 		},
+		setTLSReload: func(fn func()) {
+			w.SetTLSReloadCallback(fn)
+		},
 	}, nil
 }
@@ -4,6 +4,13 @@
 // debug settings, proxy configuration, and API keys.
 package config
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
 // SDKConfig represents the application's configuration, loaded from a YAML file.
 type SDKConfig struct {
 	// ProxyURL is the URL of an optional proxy server to use for outbound requests.
@@ -15,6 +22,9 @@ type SDKConfig struct {
 	// APIKeys is a list of keys for authenticating clients to this proxy server.
 	APIKeys []string `yaml:"api-keys" json:"api-keys"`
 
+	// ClientKeys defines client API keys with per-key model and provider restrictions.
+	ClientKeys []ClientKey `yaml:"client-keys,omitempty" json:"client-keys,omitempty"`
+
 	// Access holds request authentication provider configuration.
 	Access AccessConfig `yaml:"auth,omitempty" json:"auth,omitempty"`
 }
@@ -43,6 +53,121 @@ type AccessProvider struct {
 	Config map[string]any `yaml:"config,omitempty" json:"config,omitempty"`
 }
 
+// ClientKey is a client API key with an optional access policy. The secret is either stored in
+// Key or, for keys generated through the management API, only as KeyHash.
+type ClientKey struct {
+	// Key is the secret presented by the client.
+	Key string `yaml:"key,omitempty" json:"key,omitempty"`
+
+	// ID identifies the key without revealing it. Keys stored as a hash are known by their ID.
+	ID string `yaml:"id,omitempty" json:"id,omitempty"`
+
+	// KeyHash is the "sha256:<hex>" digest of the secret, see HashClientKey.
+	KeyHash string `yaml:"key-hash,omitempty" json:"key-hash,omitempty"`
+
+	// Name labels the key.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Owner names the person or service the key was issued to.
+	Owner string `yaml:"owner,omitempty" json:"owner,omitempty"`
+
+	// Group restricts the key to the upstream credentials of this credential group. Empty uses the
+	// credentials without a group.
+	Group string `yaml:"group,omitempty" json:"group,omitempty"`
+
+	// Scopes lists the API families the key may call: openai, claude and gemini. Empty allows all.
+	Scopes []string `yaml:"scopes,omitempty" json:"scopes,omitempty"`
+
+	// CreatedAt records when the key was issued.
+	CreatedAt *time.Time `yaml:"created-at,omitempty" json:"created-at,omitempty"`
+
+	// ExpiresAt rejects the key from this instant on. Nil never expires.
+	ExpiresAt *time.Time `yaml:"expires-at,omitempty" json:"expires-at,omitempty"`
+
+	// AllowedModels lists model name patterns the key may use; "*" matches any run of characters.
+	// Empty allows every model.
+	AllowedModels []string `yaml:"allowed-models,omitempty" json:"allowed-models,omitempty"`
+
+	// DeniedModels lists model name patterns the key may not use. It takes precedence over AllowedModels.
+	DeniedModels []string `yaml:"denied-models,omitempty" json:"denied-models,omitempty"`
+
+	// AllowedProviders lists the providers the key may be routed to. Empty allows every provider.
+	AllowedProviders []string `yaml:"allowed-providers,omitempty" json:"allowed-providers,omitempty"`
+
+	// RPM caps requests per minute for the key, overriding client-limits.rpm. Zero uses the default.
+	RPM int `yaml:"rpm,omitempty" json:"rpm,omitempty"`
+
+	// MaxConcurrentStreams caps simultaneous in-flight requests for the key, overriding
+	// client-limits.max-concurrent-streams. Zero uses the default.
+	MaxConcurrentStreams int `yaml:"max-concurrent-streams,omitempty" json:"max-concurrent-streams,omitempty"`
+
+	// Budget caps the tokens and requests the key may spend per day and month.
+	Budget ClientKeyBudget `yaml:"budget,omitempty" json:"budget,omitempty"`
+}
+
+// ClientKeyBudget holds the spend limits of a client key. Zero values are unlimited; days and months
+// follow the server's local time.
+type ClientKeyBudget struct {
+	// DailyTokens caps the total tokens reported by upstream responses per day.
+	DailyTokens int64 `yaml:"daily-tokens,omitempty" json:"daily-tokens,omitempty"`
+
+	// MonthlyTokens caps the total tokens reported by upstream responses per calendar month.
+	MonthlyTokens int64 `yaml:"monthly-tokens,omitempty" json:"monthly-tokens,omitempty"`
+
+	// DailyRequests caps the requests admitted per day.
+	DailyRequests int64 `yaml:"daily-requests,omitempty" json:"daily-requests,omitempty"`
+
+	// MonthlyRequests caps the requests admitted per calendar month.
+	MonthlyRequests int64 `yaml:"monthly-requests,omitempty" json:"monthly-requests,omitempty"`
+}
+
+// Client key scopes, one per client-facing API family.
+const (
+	ClientKeyScopeOpenAI = "openai"
+	ClientKeyScopeClaude = "claude"
+	ClientKeyScopeGemini = "gemini"
+)
+
+// ValidClientKeyScope reports whether scope names a known API family.
+func ValidClientKeyScope(scope string) bool {
+	switch scope {
+	case ClientKeyScopeOpenAI, ClientKeyScopeClaude, ClientKeyScopeGemini:
+		return true
+	}
+	return false
+}
+
+// HashClientKey returns the digest stored in ClientKey.KeyHash for secret.
+func HashClientKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Principal returns the identity requests authenticated with the key run under: the key itself for
+// plaintext keys and the ID for hashed ones.
+func (k *ClientKey) Principal() string {
+	if k == nil {
+		return ""
+	}
+	if key := strings.TrimSpace(k.Key); key != "" {
+		return key
+	}
+	if k.KeyHash != "" {
+		return strings.TrimSpace(k.ID)
+	}
+	return ""
+}
+
+// Expired reports whether the key is past its expiry at now.
+func (k *ClientKey) Expired(now time.Time) bool {
+	return k != nil && k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// Unlimited reports whether the budget sets no limit.
+func (b ClientKeyBudget) Unlimited() bool {
+	return b.DailyTokens <= 0 && b.MonthlyTokens <= 0 && b.DailyRequests <= 0 && b.MonthlyRequests <= 0
+}
+
 const (
 	// AccessProviderTypeConfigAPIKey is the built-in provider validating inline API keys.
 	AccessProviderTypeConfigAPIKey = "config-api-key"
@@ -67,6 +192,34 @@ func (c *SDKConfig) ConfigAPIKeyProvider() *AccessProvider {
 	return nil
 }
 
+// RemoveConfigAPIKeyProviders drops inline API key providers so the provider is rebuilt from
+// api-keys, keeping providers of other types.
+func (c *SDKConfig) RemoveConfigAPIKeyProviders() {
+	if c == nil || len(c.Access.Providers) == 0 {
+		return
+	}
+	var kept []AccessProvider
+	for _, provider := range c.Access.Providers {
+		if provider.Type != AccessProviderTypeConfigAPIKey {
+			kept = append(kept, provider)
+		}
+	}
+	c.Access.Providers = kept
+}
+
+// HasConfigAPIKeyProvider reports whether an inline API key provider is declared explicitly.
+func (c *SDKConfig) HasConfigAPIKeyProvider() bool {
+	if c == nil {
+		return false
+	}
+	for i := range c.Access.Providers {
+		if c.Access.Providers[i].Type == AccessProviderTypeConfigAPIKey {
+			return true
+		}
+	}
+	return false
+}
+
 // MakeInlineAPIKeyProvider constructs an inline API key provider configuration.
 // It returns nil when no keys are supplied.
 func MakeInlineAPIKeyProvider(keys []string) *AccessProvider {
@@ -80,3 +233,144 @@ func MakeInlineAPIKeyProvider(keys []string) *AccessProvider {
 	}
 	return provider
 }
+
+// InlineAccessProvider returns the inline API key provider for api-keys and client-keys, or nil
+// when neither defines a key.
+func (c *SDKConfig) InlineAccessProvider() *AccessProvider {
+	if provider := MakeInlineAPIKeyProvider(c.InlineAPIKeys()); provider != nil {
+		return provider
+	}
+	if c == nil {
+		return nil
+	}
+	for i := range c.ClientKeys {
+		if c.ClientKeys[i].Principal() != "" {
+			return &AccessProvider{Name: DefaultAccessProviderName, Type: AccessProviderTypeConfigAPIKey}
+		}
+	}
+	return nil
+}
+
+// InlineAPIKeys returns the plain api-keys followed by the keys of client-keys entries, without duplicates.
+func (c *SDKConfig) InlineAPIKeys() []string {
+	if c == nil {
+		return nil
+	}
+	if len(c.ClientKeys) == 0 {
+		return c.APIKeys
+	}
+	seen := make(map[string]struct{}, len(c.APIKeys)+len(c.ClientKeys))
+	keys := make([]string, 0, len(c.APIKeys)+len(c.ClientKeys))
+	for _, key := range c.APIKeys {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	for i := range c.ClientKeys {
+		key := strings.TrimSpace(c.ClientKeys[i].Key)
+		if key == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// IsConfigAPIKeyProvider reports whether name identifies an inline API key provider, the only
+// provider whose principals are api-keys and client-keys entries.
+func (c *SDKConfig) IsConfigAPIKeyProvider(name string) bool {
+	if c == nil || name == "" {
+		return false
+	}
+	for i := range c.Access.Providers {
+		provider := &c.Access.Providers[i]
+		if provider.Type != AccessProviderTypeConfigAPIKey {
+			continue
+		}
+		if provider.Name == name || (provider.Name == "" && name == DefaultAccessProviderName) {
+			return true
+		}
+	}
+	return name == DefaultAccessProviderName && c.InlineAccessProvider() != nil
+}
+
+// LookupClientKey returns the client-keys entry whose principal is key, or nil when the key has no
+// entry. Hashed keys are looked up by ID.
+func (c *SDKConfig) LookupClientKey(key string) *ClientKey {
+	if c == nil || key == "" {
+		return nil
+	}
+	for i := range c.ClientKeys {
+		if c.ClientKeys[i].Principal() == key {
+			return &c.ClientKeys[i]
+		}
+	}
+	return nil
+}
+
+// AllowsProvider reports whether the key may be routed to provider.
+func (k *ClientKey) AllowsProvider(provider string) bool {
+	if k == nil || len(k.AllowedProviders) == 0 {
+		return true
+	}
+	for _, allowed := range k.AllowedProviders {
+		if strings.EqualFold(strings.TrimSpace(allowed), provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsModel reports whether the key may use model.
+func (k *ClientKey) AllowsModel(model string) bool {
+	if k == nil {
+		return true
+	}
+	for _, pattern := range k.DeniedModels {
+		if MatchModelPattern(pattern, model) {
+			return false
+		}
+	}
+	if len(k.AllowedModels) == 0 {
+		return true
+	}
+	for _, pattern := range k.AllowedModels {
+		if MatchModelPattern(pattern, model) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchModelPattern matches model against a case-insensitive pattern where "*" matches any run of
+// characters, including "/".
+func MatchModelPattern(pattern, model string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	model = strings.ToLower(model)
+	if pattern == "" {
+		return false
+	}
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == model
+	}
+	if !strings.HasPrefix(model, parts[0]) {
+		return false
+	}
+	model = model[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(model, part)
+		if idx < 0 {
+			return false
+		}
+		model = model[idx+len(part):]
+	}
+	return len(model) >= len(last) && strings.HasSuffix(model, last)
+}